@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sendyxmail/ackmanager"
+	"sendyxmail/apiv0"
+	"time"
+
+	"github.com/go-botx/botx"
+	"github.com/go-botx/botx/models"
+)
+
+// handleAck records acknowledgement and marks every message with Ack button as acknowledged.
+func handleAck(b *botx.Bot, req *models.CommandRequest) {
+	chatId := req.From.GroupChatId
+	ackId, err := apiv0.DecodeAckButtonData(req.Command.Data)
+	if err != nil {
+		log.Printf("failed to decode ack button in chat %s: %s", chatId, err.Error())
+		return
+	}
+	actor := ackmanager.Actor{
+		UserHuid: commandSenderHuid(req),
+		Name:     req.From.Username,
+	}
+	if actor.Name == "" {
+		actor.Name = req.From.AdLogin
+	}
+
+	ack, changed, err := am.Acknowledge(ackId, actor)
+	responseString := ""
+	if errors.Is(err, ackmanager.ErrNotFound) {
+		responseString = "ack_not_found"
+	} else if err != nil {
+		log.Printf("failed to acknowledge %s: %s", ackId, err.Error())
+		responseString = "error"
+	} else if !changed {
+		responseString = "already_acked"
+	}
+	if responseString != "" {
+		text := getLocalizedMessage(req.From.Locale, responseString)
+		if responseString == "already_acked" {
+			text = fmt.Sprintf(text, formatAckNote(req.From.Locale, ack))
+		}
+		message, err := models.NewNDRequest(chatId, text)
+		if err != nil {
+			return
+		}
+		b.SendMessageAsync(message)
+		return
+	}
+
+	body, mentionOpts := apiv0.ExpandMentions(&apiConfig, ack.Body+"\n\n"+formatAckNote(req.From.Locale, ack))
+	for _, sent := range ack.Messages {
+		edit, err := models.NewEditRequest(sent.SyncId, body, mentionOpts...)
+		if err != nil {
+			continue
+		}
+		err = b.EditMessage(edit)
+		if err != nil {
+			log.Printf("failed to mark message %s as acknowledged: %s", sent.SyncId, err.Error())
+		}
+	}
+}
+
+func formatAckNote(locale string, ack ackmanager.Ack) string {
+	if ack.AckedBy == nil || ack.AckedAt == nil {
+		return ""
+	}
+	return fmt.Sprintf(getLocalizedMessage(locale, "acked_by"), ack.AckedBy.Name, ack.AckedAt.Format(time.DateTime+" MST"))
+}
@@ -0,0 +1,207 @@
+package ackmanager
+
+import (
+	"errors"
+	"log"
+	"sendyxmail/filestore"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Ack is a message that requires acknowledgement.
+type Ack struct {
+	Id          uuid.UUID     `json:"id"`
+	To          string        `json:"to"`
+	Fallback    string        `json:"fallback,omitempty"`
+	Body        string        `json:"body"`
+	CreatedAt   time.Time     `json:"created_at"`
+	Deadline    time.Time     `json:"deadline,omitzero"`
+	Messages    []SentMessage `json:"messages"`
+	AckedBy     *Actor        `json:"acked_by,omitempty"`
+	AckedAt     *time.Time    `json:"acked_at,omitempty"`
+	EscalatedAt *time.Time    `json:"escalated_at,omitempty"`
+}
+
+// SentMessage is a chat message that carries Ack button.
+type SentMessage struct {
+	ChatId uuid.UUID `json:"chat_id"`
+	SyncId uuid.UUID `json:"sync_id"`
+}
+
+// Actor is a user who acknowledged message.
+type Actor struct {
+	UserHuid string `json:"user_huid,omitempty"`
+	Name     string `json:"name"`
+}
+
+func (a *Ack) Acknowledged() bool {
+	return a.AckedAt != nil
+}
+
+// TimeoutFunc is called once for every ack which deadline is passed without acknowledgement.
+type TimeoutFunc func(ack Ack)
+
+type AckManager struct {
+	file      string
+	retention time.Duration
+	acks      map[uuid.UUID]*Ack
+	onTimeout TimeoutFunc
+	mutex     sync.RWMutex
+}
+
+var ErrNotFound = errors.New("ack not found")
+
+// Run loads acks from file and starts checking deadlines.
+// Acknowledged and escalated acks are forgotten after retention.
+func Run(file string, retention time.Duration, onTimeout TimeoutFunc) (*AckManager, error) {
+	am := &AckManager{
+		file:      file,
+		retention: retention,
+		acks:      map[uuid.UUID]*Ack{},
+		onTimeout: onTimeout,
+	}
+	err := filestore.LoadJSON(am.file, &am.acks)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			time.Sleep(30 * time.Second)
+			am.checkDeadlines()
+		}
+	}()
+	return am, nil
+}
+
+// Create registers new ack waiting for acknowledgement until deadline.
+// Zero timeout means that ack has no deadline and TimeoutFunc is never called for it.
+func (am *AckManager) Create(to string, fallback string, body string, timeout time.Duration) (Ack, error) {
+	now := time.Now().UTC()
+	ack := &Ack{
+		Id:        uuid.New(),
+		To:        to,
+		Fallback:  fallback,
+		Body:      body,
+		CreatedAt: now,
+		Messages:  []SentMessage{},
+	}
+	if timeout > 0 {
+		ack.Deadline = now.Add(timeout)
+	}
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+	am.acks[ack.Id] = ack
+	err := am.save()
+	if err != nil {
+		delete(am.acks, ack.Id)
+		return Ack{}, err
+	}
+	return *ack, nil
+}
+
+// AddMessage remembers chat message with Ack button, so it can be edited after acknowledgement.
+func (am *AckManager) AddMessage(id uuid.UUID, message SentMessage) error {
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+	ack, ok := am.acks[id]
+	if !ok {
+		return ErrNotFound
+	}
+	ack.Messages = append(ack.Messages, message)
+	return am.save()
+}
+
+// Remove forgets ack, for example when message with Ack button was not sent.
+func (am *AckManager) Remove(id uuid.UUID) error {
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+	delete(am.acks, id)
+	return am.save()
+}
+
+func (am *AckManager) Get(id uuid.UUID) (Ack, bool) {
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+	ack, ok := am.acks[id]
+	if !ok {
+		return Ack{}, false
+	}
+	return ack.clone(), true
+}
+
+// Acknowledge records who acknowledged message.
+// changed is false if message was already acknowledged.
+func (am *AckManager) Acknowledge(id uuid.UUID, by Actor) (ack Ack, changed bool, err error) {
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+	current, ok := am.acks[id]
+	if !ok {
+		return Ack{}, false, ErrNotFound
+	}
+	if current.Acknowledged() {
+		return current.clone(), false, nil
+	}
+	now := time.Now().UTC()
+	current.AckedBy = &by
+	current.AckedAt = &now
+	err = am.save()
+	if err != nil {
+		current.AckedBy = nil
+		current.AckedAt = nil
+		return Ack{}, false, err
+	}
+	return current.clone(), true, nil
+}
+
+func (am *AckManager) checkDeadlines() {
+	now := time.Now().UTC()
+	expired := []Ack{}
+
+	am.mutex.Lock()
+	for id, ack := range am.acks {
+		finishedAt := ack.AckedAt
+		if finishedAt == nil {
+			finishedAt = ack.EscalatedAt
+		}
+		if finishedAt != nil {
+			if now.Sub(*finishedAt) > am.retention {
+				delete(am.acks, id)
+			}
+			continue
+		}
+		if ack.Deadline.IsZero() {
+			if now.Sub(ack.CreatedAt) > am.retention {
+				delete(am.acks, id)
+			}
+			continue
+		}
+		if now.After(ack.Deadline) {
+			ack.EscalatedAt = &now
+			expired = append(expired, ack.clone())
+		}
+	}
+	err := am.save()
+	am.mutex.Unlock()
+	if err != nil {
+		log.Printf("failed to save acks: %s", err.Error())
+	}
+
+	for _, ack := range expired {
+		if am.onTimeout != nil {
+			am.onTimeout(ack)
+		}
+	}
+}
+
+func (am *AckManager) save() error {
+	return filestore.SaveJSON(am.file, am.acks)
+}
+
+func (a *Ack) clone() Ack {
+	clone := *a
+	clone.Messages = slices.Clone(a.Messages)
+	return clone
+}
@@ -0,0 +1,113 @@
+package aliasmanager
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"net/mail"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/google/uuid"
+)
+
+type AliasManager struct {
+	refreshInterval     time.Duration
+	file                string
+	groupChatMailSuffix string
+	aliases             map[string][]string
+	mutex               sync.RWMutex
+}
+
+type aliasRecord struct {
+	Address string   `yaml:"address"`
+	Members []string `yaml:"members"`
+}
+
+// Run loads aliases from file and re-reads it every refreshInterval.
+// Members that are bare chat UUIDs are turned into addresses with groupChatMailSuffix.
+func Run(aliasFile string, groupChatMailSuffix string, refreshInterval time.Duration) (*AliasManager, error) {
+	am := &AliasManager{
+		file:                aliasFile,
+		groupChatMailSuffix: groupChatMailSuffix,
+		aliases:             map[string][]string{},
+		refreshInterval:     refreshInterval,
+	}
+	err := am.reloadAliases()
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			time.Sleep(am.refreshInterval)
+			err := am.reloadAliases()
+			if err != nil {
+				log.Printf("failed reloading aliases: %s\n", err.Error())
+			}
+		}
+	}()
+	return am, nil
+}
+
+// Resolve returns member addresses of alias.
+func (am *AliasManager) Resolve(address string) ([]string, bool) {
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+	members, ok := am.aliases[strings.ToLower(address)]
+	return slices.Clone(members), ok
+}
+
+func (am *AliasManager) reloadAliases() error {
+	data, err := os.ReadFile(am.file)
+	if err != nil {
+		return err
+	}
+	var records []aliasRecord
+	err = yaml.Unmarshal(data, &records)
+	if err != nil {
+		return err
+	}
+
+	newAliases := map[string][]string{}
+	for idx, record := range records {
+		address, err := mail.ParseAddress(record.Address)
+		if err != nil {
+			return fmt.Errorf("alias number %d in file %s has invalid address: %w", idx+1, am.file, err)
+		}
+		key := strings.ToLower(address.Address)
+		if _, ok := newAliases[key]; ok {
+			return fmt.Errorf("alias %s is defined more than once in file %s", key, am.file)
+		}
+		members := []string{}
+		for _, member := range record.Members {
+			member = strings.ToLower(strings.TrimSpace(member))
+			if chatId, err := uuid.Parse(member); err == nil && chatId.String() == member {
+				member = member + am.groupChatMailSuffix
+			}
+			if member != "" && !slices.Contains(members, member) {
+				members = append(members, member)
+			}
+		}
+		if len(members) == 0 {
+			return fmt.Errorf("alias %s in file %s has no members", key, am.file)
+		}
+		newAliases[key] = members
+	}
+
+	am.mutex.RLock()
+	unchanged := maps.EqualFunc(newAliases, am.aliases, slices.Equal)
+	am.mutex.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	am.mutex.Lock()
+	defer am.mutex.Unlock()
+	am.aliases = newAliases
+	log.Printf("updated aliases from %s\n", am.file)
+	return nil
+}
@@ -0,0 +1,108 @@
+package apiv0
+
+import (
+	"encoding/json"
+	"errors"
+	"sendyxmail/ackmanager"
+	"time"
+
+	"github.com/go-botx/botx/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// AckCommand is hidden bot command sent by pressing Ack button.
+const AckCommand = "/_ack"
+
+const (
+	ackButtonLabel    = "✅ Ack"
+	defaultAckTimeout = 15 * time.Minute
+)
+
+// AckButtonData is data of Ack button.
+type AckButtonData struct {
+	AckId uuid.UUID `json:"ack_id"`
+}
+
+// DecodeAckButtonData extracts ack id from data of AckCommand command received by bot.
+func DecodeAckButtonData(commandData any) (uuid.UUID, error) {
+	raw, err := json.Marshal(commandData)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	var data AckButtonData
+	err = json.Unmarshal(raw, &data)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if data.AckId == uuid.Nil {
+		return uuid.Nil, errors.New("ack button data has no ack_id")
+	}
+	return data.AckId, nil
+}
+
+func ackButtonOption(ackId uuid.UUID) models.NDRequestOption {
+	return models.WithNDBubbleRow(models.NewCommandButton(ackButtonLabel, AckCommand, AckButtonData{AckId: ackId}))
+}
+
+func deliverWithAck(ctxData *APIConfig, message Message, chatId uuid.UUID, ndOpts []models.NDRequestOption, requireStatus bool) (DeliveryResult, error) {
+	am := ctxData.AckManager
+	timeout := defaultAckTimeout
+	if message.AckTimeout > 0 {
+		timeout = time.Duration(message.AckTimeout) * time.Minute
+	}
+	fallback := message.AckFallback
+	if fallback == "" {
+		fallback = ctxData.AckFallbackAddress
+	}
+	ack, err := am.Create(message.To, fallback, message.Body, timeout)
+	if err != nil {
+		return DeliveryResult{}, err
+	}
+	ndOpts = append(ndOpts, ackButtonOption(ack.Id))
+	syncId, err := sendToChat(ctxData, chatId, message.Body, ndOpts, requireStatus)
+	if err != nil {
+		_ = am.Remove(ack.Id)
+		return DeliveryResult{}, err
+	}
+	err = am.AddMessage(ack.Id, ackmanager.SentMessage{ChatId: chatId, SyncId: syncId})
+	if err != nil {
+		return DeliveryResult{}, err
+	}
+	return DeliveryResult{ChatId: chatId, SyncId: syncId, AckId: &ack.Id}, nil
+}
+
+// DeliverAckReminder re-sends message which was not acknowledged in time.
+// Message is sent to fallback address when it is set, otherwise to original recipient.
+func DeliverAckReminder(config *APIConfig, ack ackmanager.Ack) error {
+	to := ack.To
+	if ack.Fallback != "" {
+		to = ack.Fallback
+	}
+	chatId, err := resolveRecipient(config, to)
+	if err != nil {
+		return err
+	}
+	body := "⏰ Not acknowledged in time / Не подтверждено вовремя\n\n" + ack.Body
+	syncId, err := sendToChat(config, chatId, body, []models.NDRequestOption{ackButtonOption(ack.Id)}, true)
+	if err != nil {
+		return err
+	}
+	return config.AckManager.AddMessage(ack.Id, ackmanager.SentMessage{ChatId: chatId, SyncId: syncId})
+}
+
+func apiGetAckHandler(c *fiber.Ctx) error {
+	ctxData := extractAppCtxData(c)
+	if ctxData.AckManager == nil {
+		return sendJsonResponseString(c, fiber.StatusNotImplemented, "acknowledgements are not configured")
+	}
+	ackId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, "ack id is not UUID")
+	}
+	ack, ok := ctxData.AckManager.Get(ackId)
+	if !ok {
+		return sendJsonResponseString(c, fiber.StatusNotFound, "ack not found")
+	}
+	return c.Status(fiber.StatusOK).JSON(ack)
+}
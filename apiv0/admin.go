@@ -0,0 +1,66 @@
+package apiv0
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func authenticateAdmin(c *fiber.Ctx) error {
+	ctxData := extractAppCtxData(c)
+	if ctxData.CheckAdminToken == nil {
+		return errors.New("admin token check function not configured")
+	}
+	tokenString := extractBearerToken(c.Get(fiber.HeaderAuthorization, ""))
+	if err := ctxData.CheckAdminToken(tokenString); err != nil {
+		return sendJsonResponseString(c, fiber.StatusForbidden, "provided token is not allowed to use admin API")
+	}
+	return c.Next()
+}
+
+func apiAdminListChatsHandler(c *fiber.Ctx) error {
+	ctxData := extractAppCtxData(c)
+	if ctxData.ChatRegistry == nil {
+		return sendJsonResponseString(c, fiber.StatusNotImplemented, "chat registry is not configured")
+	}
+	return c.Status(fiber.StatusOK).JSON(ctxData.ChatRegistry.List())
+}
+
+func apiAdminCacheStatsHandler(c *fiber.Ctx) error {
+	ctxData := extractAppCtxData(c)
+	if ctxData.UserCache == nil {
+		return sendJsonResponseString(c, fiber.StatusNotImplemented, "user cache is not configured")
+	}
+	return c.Status(fiber.StatusOK).JSON(ctxData.UserCache.Stats())
+}
+
+func apiAdminPurgeCacheHandler(c *fiber.Ctx) error {
+	ctxData := extractAppCtxData(c)
+	if ctxData.UserCache == nil {
+		return sendJsonResponseString(c, fiber.StatusNotImplemented, "user cache is not configured")
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"result":  "OK",
+		"removed": ctxData.UserCache.Purge(),
+	})
+}
+
+func apiAdminInvalidateCacheHandler(c *fiber.Ctx) error {
+	ctxData := extractAppCtxData(c)
+	if ctxData.UserCache == nil {
+		return sendJsonResponseString(c, fiber.StatusNotImplemented, "user cache is not configured")
+	}
+	key, err := url.PathUnescape(c.Params("key"))
+	if err != nil {
+		return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, "unable to decode key")
+	}
+	addr, _, err := parseRecipientAddress(key)
+	if err != nil {
+		return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, err.Error())
+	}
+	if !ctxData.UserCache.Invalidate(addr) {
+		return sendJsonResponseString(c, fiber.StatusNotFound, "key is not cached")
+	}
+	return sendJsonResponseString(c, fiber.StatusOK, "OK")
+}
@@ -0,0 +1,95 @@
+package apiv0
+
+import (
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// MemberResult is delivery result for one member of alias or one subscriber of topic.
+type MemberResult struct {
+	To        string     `json:"to"`
+	Status    int        `json:"status"`
+	Result    string     `json:"result"`
+	AckId     *uuid.UUID `json:"ack_id,omitempty"`
+	ChatId    *uuid.UUID `json:"chat_id,omitempty"`
+	HeldUntil *time.Time `json:"held_until,omitempty"`
+	DigestAt  *time.Time `json:"digest_at,omitempty"`
+	// Code is "muted" and Mute describes the mute if member is muted
+	Code   string       `json:"code,omitempty"`
+	Queued bool         `json:"queued,omitempty"`
+	Mute   *MuteDetails `json:"mute,omitempty"`
+}
+
+// resolveAlias returns members of alias address.
+func resolveAlias(ctxData *APIConfig, to string) (string, []string, bool) {
+	if ctxData.AliasManager == nil {
+		return "", nil, false
+	}
+	mailContact, err := mail.ParseAddress(to)
+	if err != nil {
+		return "", nil, false
+	}
+	addr := strings.ToLower(mailContact.Address)
+	members, ok := ctxData.AliasManager.Resolve(addr)
+	return addr, members, ok
+}
+
+// deliverToMembers sends message to every member of alias or topic and collects per-member results.
+// Members are not resolved as aliases again.
+func deliverToMembers(ctxData *APIConfig, message Message, alias string, members []string, origin *messageOrigin, requireStatus bool) (DeliveryResult, error) {
+	err := checkAllowedAddress(ctxData, alias)
+	if err != nil {
+		return DeliveryResult{}, err
+	}
+	successStatus := fiber.StatusAccepted
+	if requireStatus {
+		successStatus = fiber.StatusCreated
+	}
+	result := DeliveryResult{Members: []MemberResult{}}
+	for _, member := range members {
+		memberMessage := message
+		memberMessage.To = member
+		memberResult := MemberResult{
+			To:     member,
+			Status: successStatus,
+			Result: "OK",
+		}
+		delivered, err := deliverSingle(ctxData, memberMessage, origin, requireStatus)
+		if queued, ok := queueIfMuted(ctxData, memberMessage, origin, err); ok {
+			memberResult.Status = fiber.StatusAccepted
+			memberResult.Result = "queued until unmuted"
+			memberResult.Code = "muted"
+			memberResult.Queued = true
+			memberResult.Mute = queued.Queued
+		} else if err != nil {
+			memberResult.Status = deliveryErrorStatus(err)
+			memberResult.Result = err.Error()
+			if details, muted := mutedDetails(err); muted {
+				memberResult.Code = "muted"
+				memberResult.Mute = details
+			}
+		}
+		memberResult.AckId = delivered.AckId
+		memberResult.HeldUntil = delivered.HeldUntil
+		memberResult.DigestAt = delivered.DigestAt
+		result.Members = append(result.Members, memberResult)
+	}
+	return result, nil
+}
+
+// delivered reports if message was delivered to at least one member.
+func (r DeliveryResult) delivered() bool {
+	if r.Members == nil {
+		return true
+	}
+	for _, member := range r.Members {
+		if member.Status < 300 {
+			return true
+		}
+	}
+	return false
+}
@@ -5,24 +5,58 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/mail"
+	"sendyxmail/ackmanager"
+	"sendyxmail/aliasmanager"
+	"sendyxmail/chataliasmanager"
+	"sendyxmail/chatregistry"
+	"sendyxmail/digestmanager"
+	"sendyxmail/escalationmanager"
+	"sendyxmail/mutemanager"
+	"sendyxmail/queuemanager"
+	"sendyxmail/quietmanager"
+	"sendyxmail/rotationmanager"
+	"sendyxmail/threadmanager"
+	"sendyxmail/tokenmanager"
+	"sendyxmail/topicmanager"
+	"sendyxmail/usercache"
 	"strings"
+	"time"
 
 	"github.com/go-botx/botx"
-	"github.com/go-botx/botx/models"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
 type CheckBearerTokenFunc func(token string) error
 type CheckAllowedSendFunc func(ident string) error
+type CheckAllowedSenderFunc func(chatId uuid.UUID, sender string) error
+type SenderNameFunc func(tokenId string) string
 
 type APIConfig struct {
 	Bot                      *botx.Bot
 	GroupChatMailSuffix      string
 	CheckBearerToken         CheckBearerTokenFunc
+	CheckAdminToken          CheckBearerTokenFunc
 	MetadataEncryptionSecret string
 	CheckAllowedSend         CheckAllowedSendFunc
+	AckManager               *ackmanager.AckManager
+	AckFallbackAddress       string
+	EscalationManager        *escalationmanager.EscalationManager
+	RotationManager          *rotationmanager.RotationManager
+	RotationMailSuffix       string
+	AliasManager             *aliasmanager.AliasManager
+	ChatAliasManager         *chataliasmanager.ChatAliasManager
+	ChatAliasMailSuffix      string
+	ChatRegistry             *chatregistry.ChatRegistry
+	UserCache                *usercache.UserCache
+	ThreadManager            *threadmanager.ThreadManager
+	QuietManager             *quietmanager.QuietManager
+	SenderName               SenderNameFunc
+	CheckAllowedSender       CheckAllowedSenderFunc
+	TopicManager             *topicmanager.TopicManager
+	DigestManager            *digestmanager.DigestManager
+	MuteManager              *mutemanager.MuteManager
+	QueueManager             *queuemanager.QueueManager
 }
 
 var apiCtxConfigKey = uuid.MustParse("a30f42ca-d68a-4229-b868-add3792f512a") // This is random UUID
@@ -32,8 +66,27 @@ func New(config APIConfig) *fiber.App {
 		Bot:                      config.Bot,
 		GroupChatMailSuffix:      config.GroupChatMailSuffix,
 		CheckBearerToken:         config.CheckBearerToken,
+		CheckAdminToken:          config.CheckAdminToken,
 		MetadataEncryptionSecret: config.MetadataEncryptionSecret,
 		CheckAllowedSend:         config.CheckAllowedSend,
+		AckManager:               config.AckManager,
+		AckFallbackAddress:       config.AckFallbackAddress,
+		EscalationManager:        config.EscalationManager,
+		RotationManager:          config.RotationManager,
+		RotationMailSuffix:       config.RotationMailSuffix,
+		AliasManager:             config.AliasManager,
+		ChatAliasManager:         config.ChatAliasManager,
+		ChatAliasMailSuffix:      config.ChatAliasMailSuffix,
+		ChatRegistry:             config.ChatRegistry,
+		UserCache:                config.UserCache,
+		ThreadManager:            config.ThreadManager,
+		QuietManager:             config.QuietManager,
+		SenderName:               config.SenderName,
+		CheckAllowedSender:       config.CheckAllowedSender,
+		TopicManager:             config.TopicManager,
+		DigestManager:            config.DigestManager,
+		MuteManager:              config.MuteManager,
+		QueueManager:             config.QueueManager,
 	}
 	api := fiber.New()
 	api.Use(injectAppCtxData(apiConfig))
@@ -41,6 +94,27 @@ func New(config APIConfig) *fiber.App {
 	api.Use(authenticateClient)
 	api.Post("/message", apiPostMessageHandlerWithoutStatus)
 	api.Post("/message/with-status", apiPostMessageHandlerWithStatus)
+	api.Post("/resolve", apiPostResolveHandler)
+	api.Get("/ack/:id", apiGetAckHandler)
+	api.Get("/escalation/:id", apiGetEscalationHandler)
+	api.Post("/chats", apiPostChatHandler)
+	api.Post("/chats/:chat/members", apiAddChatMembersHandler)
+	api.Delete("/chats/:chat/members", apiRemoveChatMembersHandler)
+	api.Get("/rotations", apiListRotationsHandler)
+	api.Get("/rotations/:name", apiGetRotationHandler)
+
+	admin := api.Group("/admin", authenticateAdmin)
+	admin.Get("/chats", apiAdminListChatsHandler)
+	admin.Put("/rotations/:name", apiPutRotationHandler)
+	admin.Delete("/rotations/:name", apiDeleteRotationHandler)
+	admin.Get("/cache", apiAdminCacheStatsHandler)
+	admin.Delete("/cache", apiAdminPurgeCacheHandler)
+	admin.Delete("/cache/:key", apiAdminInvalidateCacheHandler)
+	admin.Get("/mutes", apiAdminListMutesHandler)
+	admin.Post("/mutes/import", apiAdminImportMutesHandler)
+	admin.Put("/mutes/:entry", apiAdminMuteHandler)
+	admin.Delete("/mutes/:entry", apiAdminUnmuteHandler)
+	admin.Get("/mutes/history", apiAdminMuteHistoryHandler)
 	return api
 }
 
@@ -55,6 +129,21 @@ func sendJsonResponseString(c *fiber.Ctx, statusCode int, result string) error {
 	return c.Status(statusCode).Send(payload)
 }
 
+type messageResponse struct {
+	Result       string         `json:"result"`
+	DryRun       bool           `json:"dry_run,omitempty"`
+	ChatId       *uuid.UUID     `json:"chat_id,omitempty"`
+	HeldUntil    *time.Time     `json:"held_until,omitempty"`
+	DigestAt     *time.Time     `json:"digest_at,omitempty"`
+	AckId        *uuid.UUID     `json:"ack_id,omitempty"`
+	EscalationId *uuid.UUID     `json:"escalation_id,omitempty"`
+	Members      []MemberResult `json:"members,omitempty"`
+	// Queued and Mute are set when dry run finds that message would be queued until unmuted.
+	// Mute alone is set when escalation started although `to` is muted.
+	Queued bool         `json:"queued,omitempty"`
+	Mute   *MuteDetails `json:"mute,omitempty"`
+}
+
 func injectAppCtxData(data *APIConfig) func(*fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
 		c.Locals(apiCtxConfigKey, data)
@@ -78,122 +167,93 @@ func extractAppCtxData(c *fiber.Ctx) *APIConfig {
 }
 
 func apiPostMessageHandlerWithStatus(c *fiber.Ctx) error {
-	return apiPostMessageHandler(c, true)
+	return apiPostMessageHandler(c, true, false)
 }
 
 func apiPostMessageHandlerWithoutStatus(c *fiber.Ctx) error {
-	return apiPostMessageHandler(c, false)
+	return apiPostMessageHandler(c, false, false)
 }
 
-func apiPostMessageHandler(c *fiber.Ctx, requireStatus bool) error {
+func apiPostResolveHandler(c *fiber.Ctx) error {
+	return apiPostMessageHandler(c, true, true)
+}
+
+func apiPostMessageHandler(c *fiber.Ctx, requireStatus bool, forceDryRun bool) error {
 	ctxData := extractAppCtxData(c)
-	b := ctxData.Bot
 
 	var message Message
 	if err := c.BodyParser(&message); err != nil {
 		return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, "unable to parse json")
 	}
-	mailContact, err := mail.ParseAddress(message.To)
-	if err != nil {
-		return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, "unable to parse mail address")
+	if forceDryRun {
+		message.DryRun = true
 	}
 
-	addr := strings.ToLower(mailContact.Address)
-
-	if ctxData.CheckAllowedSend != nil {
-		err = ctxData.CheckAllowedSend(addr)
-		if err != nil {
-			return sendJsonResponseString(c, fiber.StatusUnavailableForLegalReasons, err.Error())
-		}
+	tokenId := requestTokenId(c)
+	origin := &messageOrigin{
+		TokenId:  tokenId,
+		Sender:   senderName(ctxData, tokenId),
+		Metadata: loadEncryptedMetadataFromCtx(c),
 	}
-
-	var chatId uuid.UUID
-	// Decide if it is UUID-Group-like or not:
-	addrPrefix, ok := strings.CutSuffix(addr, ctxData.GroupChatMailSuffix)
-	if !ok {
-		users, err := b.FindUsersByMails([]string{addr})
-		if err != nil {
-			return sendJsonResponseString(c, fiber.StatusInternalServerError, err.Error())
-		}
-		if len(users) <= 0 {
-			return sendJsonResponseString(c, fiber.StatusNotFound, "no users found")
-		}
-		if len(users) != 1 {
-			return sendJsonResponseString(c, fiber.StatusExpectationFailed, "found more than one recepients")
-		}
-		if users[0].UserKind != "cts_user" {
-			return sendJsonResponseString(c, fiber.StatusPreconditionRequired, "user is not cts_user")
-		}
-		chatId, err = b.CreateChatWithUser(users[0])
-		if err != nil {
-			return sendJsonResponseString(c, fiber.StatusServiceUnavailable, err.Error())
-		}
-	} else {
-		chatId, err = uuid.Parse(addrPrefix)
-		if chatId.String() != addrPrefix {
-			sendJsonResponseString(c, fiber.StatusUnprocessableEntity, fmt.Sprintf("chat_id '%s' in address '%s' is not recognized as UUID: %s", addrPrefix, mailContact, err.Error()))
-		}
+	result, err := deliverMessage(ctxData, message, origin, requireStatus)
+	if err != nil {
+		return deliveryErrorResponse(c, err)
 	}
-
-	if ctxData.CheckAllowedSend != nil {
-		err = ctxData.CheckAllowedSend(chatId.String())
-		if err != nil {
-			return sendJsonResponseString(c, fiber.StatusUnavailableForLegalReasons, err.Error())
-		}
+	response := messageResponse{
+		Result:       "OK",
+		DryRun:       result.DryRun,
+		AckId:        result.AckId,
+		EscalationId: result.EscalationId,
+		Members:      result.Members,
+		Mute:         result.Muted,
 	}
-
-	// Create Message
-
-	ndOpts := []models.NDRequestOption{}
-	if len(message.Buttons) > 0 {
-		for _, row := range message.Buttons {
-			if len(row) > 0 {
-				ndButtonRow := models.NDButtonRow{}
-				for _, button := range row {
-					opts := []models.NDButtonOption{}
-					if button.TextAlign != "" {
-						opts = append(opts, models.WithButtonContentAlign(models.NDButtonAlign(button.TextAlign)))
-					}
-					if button.TextColor != "" {
-						opts = append(opts, models.WithButtonFontColor(button.TextColor))
-					}
-					if button.BackgroundColor != "" {
-						opts = append(opts, models.WithButtonBackgroundColor(button.BackgroundColor))
-					}
-					if button.AlertText != "" {
-						opts = append(opts, models.WithButtonAlert(button.AlertText))
-					}
-					if button.HorizontalSize != 0 {
-						opts = append(opts, models.WithButtonHorizontalSize(button.HorizontalSize))
-					}
-					ndButton := models.NewLinkButton(button.Label, button.Link, opts...)
-					ndButtonRow = append(ndButtonRow, ndButton)
-				}
-				ndOpts = append(ndOpts, models.WithNDBubbleRow(ndButtonRow...))
-			}
-		}
+	if result.DryRun {
+		return dryRunResponse(c, response, result)
 	}
-
-	ndOpts = append(ndOpts, models.WithNDMetadata(loadEncryptedMetadataFromCtx(c)))
-
-	ndr, err := models.NewNDRequest(chatId, message.Body, ndOpts...)
-	if err != nil {
-		return err
+	if result.Queued != nil {
+		return c.Status(fiber.StatusAccepted).JSON(mutedResponse{
+			Result:      "queued until unmuted",
+			Code:        "muted",
+			Queued:      true,
+			MuteDetails: result.Queued,
+		})
+	}
+	if !result.delivered() {
+		response.Result = "not delivered to any member"
+		return c.Status(fiber.StatusFailedDependency).JSON(response)
+	}
+	if result.HeldUntil != nil {
+		response.Result = "held until quiet hours end"
+		response.HeldUntil = result.HeldUntil
+		return c.Status(fiber.StatusAccepted).JSON(response)
+	}
+	if result.DigestAt != nil {
+		response.Result = "collected for digest"
+		response.DigestAt = result.DigestAt
+		return c.Status(fiber.StatusAccepted).JSON(response)
 	}
-
 	if !requireStatus {
-		_, err = b.SendMessageAsync(ndr)
-		if err != nil {
-			return sendJsonResponseString(c, fiber.StatusServiceUnavailable, err.Error())
-		}
-		return sendJsonResponseString(c, fiber.StatusAccepted, "OK")
-	} else {
-		_, err = b.SendMessageSync(ndr)
-		if err != nil {
-			return sendJsonResponseString(c, fiber.StatusServiceUnavailable, err.Error())
-		}
-		return sendJsonResponseString(c, fiber.StatusCreated, "OK")
+		return c.Status(fiber.StatusAccepted).JSON(response)
+	}
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// dryRunResponse tells what would happen to the message.
+func dryRunResponse(c *fiber.Ctx, response messageResponse, result DeliveryResult) error {
+	if !result.delivered() {
+		response.Result = "not delivered to any member"
+		return c.Status(fiber.StatusFailedDependency).JSON(response)
+	}
+	if result.ChatId != uuid.Nil {
+		response.ChatId = &result.ChatId
+	}
+	response.HeldUntil = result.HeldUntil
+	response.DigestAt = result.DigestAt
+	if result.Queued != nil {
+		response.Queued = true
+		response.Mute = result.Queued
 	}
+	return c.Status(fiber.StatusOK).JSON(response)
 }
 
 func authenticateClient(c *fiber.Ctx) error {
@@ -227,3 +287,8 @@ func extractBearerToken(authHeader string) string {
 
 	return token
 }
+
+// requestTokenId returns token id of bearer token of request.
+func requestTokenId(c *fiber.Ctx) string {
+	return tokenmanager.TokenId(extractBearerToken(c.Get(fiber.HeaderAuthorization, "")))
+}
@@ -0,0 +1,101 @@
+package apiv0
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+
+	"github.com/go-botx/botx/models"
+)
+
+// ButtonCommand is hidden bot command sent by pressing data button.
+// Button label, command and data chosen by sender are encrypted into button data.
+const ButtonCommand = "/_button"
+
+// ButtonPress is decoded data of pressed button.
+type ButtonPress struct {
+	TokenId string `json:"token_id"`
+	Command string `json:"command"`
+	Label   string `json:"label"`
+	Data    any    `json:"data,omitempty"`
+}
+
+type buttonCommandData struct {
+	Payload string `json:"payload"`
+}
+
+func buildButtonOptions(ctxData *APIConfig, message Message, origin *messageOrigin) ([]models.NDRequestOption, error) {
+	aesKey := sha256.Sum256([]byte(ctxData.MetadataEncryptionSecret))
+	ndOpts := []models.NDRequestOption{}
+	for _, row := range message.Buttons {
+		if len(row) <= 0 {
+			continue
+		}
+		ndButtonRow := models.NDButtonRow{}
+		for _, button := range row {
+			opts := []models.NDButtonOption{}
+			if button.TextAlign != "" {
+				opts = append(opts, models.WithButtonContentAlign(models.NDButtonAlign(button.TextAlign)))
+			}
+			if button.TextColor != "" {
+				opts = append(opts, models.WithButtonFontColor(button.TextColor))
+			}
+			if button.BackgroundColor != "" {
+				opts = append(opts, models.WithButtonBackgroundColor(button.BackgroundColor))
+			}
+			if button.AlertText != "" {
+				opts = append(opts, models.WithButtonAlert(button.AlertText))
+			}
+			if button.HorizontalSize != 0 {
+				opts = append(opts, models.WithButtonHorizontalSize(button.HorizontalSize))
+			}
+			if button.Command == "" {
+				ndButtonRow = append(ndButtonRow, models.NewLinkButton(button.Label, button.Link, opts...))
+				continue
+			}
+			payload, err := json.Marshal(&ButtonPress{
+				TokenId: origin.TokenId,
+				Command: button.Command,
+				Label:   button.Label,
+				Data:    button.Data,
+			})
+			if err != nil {
+				return nil, err
+			}
+			encryptedPayload, err := encryptPayload(aesKey, payload)
+			if err != nil {
+				return nil, err
+			}
+			ndButtonRow = append(ndButtonRow, models.NewCommandButton(button.Label, ButtonCommand, buttonCommandData{Payload: encryptedPayload}, opts...))
+		}
+		ndOpts = append(ndOpts, models.WithNDBubbleRow(ndButtonRow...))
+	}
+	return ndOpts, nil
+}
+
+// DecodeButtonPress decrypts data of pressed button.
+// commandData is data of ButtonCommand command received by bot.
+func DecodeButtonPress(metadataEncryptionSecret string, commandData any) (*ButtonPress, error) {
+	raw, err := json.Marshal(commandData)
+	if err != nil {
+		return nil, err
+	}
+	var data buttonCommandData
+	err = json.Unmarshal(raw, &data)
+	if err != nil {
+		return nil, err
+	}
+	if data.Payload == "" {
+		return nil, errors.New("button data has no payload")
+	}
+	payload, err := decryptPayload(sha256.Sum256([]byte(metadataEncryptionSecret)), data.Payload)
+	if err != nil {
+		return nil, err
+	}
+	press := &ButtonPress{}
+	err = json.Unmarshal(payload, press)
+	if err != nil {
+		return nil, err
+	}
+	return press, nil
+}
@@ -0,0 +1,190 @@
+package apiv0
+
+import (
+	"log"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/go-botx/botx/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type chatRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Members     []string `json:"members"`
+}
+
+type chatMembersRequest struct {
+	Members []string `json:"members"`
+}
+
+type chatResponse struct {
+	Result  string         `json:"result"`
+	ChatId  *uuid.UUID     `json:"chat_id,omitempty"`
+	Address string         `json:"address,omitempty"`
+	Members []MemberResult `json:"members,omitempty"`
+}
+
+// resolveMembers finds users for member addresses.
+// Members which can not be resolved are reported in results and skipped.
+func resolveMembers(ctxData *APIConfig, members []string) ([]uuid.UUID, []MemberResult) {
+	huids := []uuid.UUID{}
+	results := []MemberResult{}
+	for _, member := range members {
+		result := MemberResult{
+			To:     member,
+			Status: fiber.StatusOK,
+			Result: "OK",
+		}
+		huid, err := resolveMember(ctxData, member)
+		if err != nil {
+			result.Status = deliveryErrorStatus(err)
+			result.Result = err.Error()
+		} else if !slices.Contains(huids, huid) {
+			huids = append(huids, huid)
+		}
+		results = append(results, result)
+	}
+	return huids, results
+}
+
+func resolveMember(ctxData *APIConfig, member string) (uuid.UUID, error) {
+	addr, _, err := parseRecipientAddress(member)
+	if err != nil {
+		return uuid.Nil, newDeliveryError(fiber.StatusUnprocessableEntity, err.Error())
+	}
+	err = checkAllowedAddress(ctxData, addr)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	user, err := findSingleUser(ctxData, addr)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return user.UserHuid, nil
+}
+
+// resolveChatParam accepts chat UUID, chat address or readable chat address.
+func resolveChatParam(ctxData *APIConfig, param string) (uuid.UUID, error) {
+	param, err := url.PathUnescape(param)
+	if err != nil {
+		return uuid.Nil, newDeliveryError(fiber.StatusUnprocessableEntity, "unable to decode chat")
+	}
+	param = strings.ToLower(strings.TrimSpace(param))
+	chatId, err := uuid.Parse(strings.TrimSuffix(param, ctxData.GroupChatMailSuffix))
+	if err != nil {
+		aliasName, ok := cutChatAliasSuffix(ctxData, param)
+		if !ok {
+			return uuid.Nil, newDeliveryError(fiber.StatusUnprocessableEntity, "chat is not recognized as UUID or chat address")
+		}
+		chatId, ok = ctxData.ChatAliasManager.Resolve(aliasName)
+		if !ok {
+			return uuid.Nil, newDeliveryError(fiber.StatusNotFound, "chat alias not found")
+		}
+	}
+	return chatId, checkAllowedChat(ctxData, chatId)
+}
+
+func apiPostChatHandler(c *fiber.Ctx) error {
+	ctxData := extractAppCtxData(c)
+
+	var request chatRequest
+	if err := c.BodyParser(&request); err != nil {
+		return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, "unable to parse json")
+	}
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, "chat name is required")
+	}
+	if len(request.Members) == 0 {
+		return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, "at least one member is required")
+	}
+
+	huids, members := resolveMembers(ctxData, request.Members)
+	if len(huids) == 0 {
+		return c.Status(fiber.StatusFailedDependency).JSON(chatResponse{
+			Result:  "no members found",
+			Members: members,
+		})
+	}
+	chatId, err := ctxData.Bot.CreateChat(request.Name, request.Description, models.ChatTypeGroupChat, huids)
+	if err != nil {
+		return sendJsonResponseString(c, fiber.StatusServiceUnavailable, err.Error())
+	}
+	if ctxData.ChatRegistry != nil {
+		err = ctxData.ChatRegistry.Create(chatId, request.Name, requestTokenId(c))
+		if err != nil {
+			log.Printf("failed to register created chat %s: %s", chatId, err.Error())
+		}
+	}
+	return c.Status(fiber.StatusCreated).JSON(chatResponse{
+		Result:  "OK",
+		ChatId:  &chatId,
+		Address: chatId.String() + ctxData.GroupChatMailSuffix,
+		Members: members,
+	})
+}
+
+// mayChangeChatMembers reports if token of request created the chat or is admin token.
+func mayChangeChatMembers(ctxData *APIConfig, c *fiber.Ctx, chatId uuid.UUID) bool {
+	token := extractBearerToken(c.Get(fiber.HeaderAuthorization, ""))
+	if ctxData.CheckAdminToken != nil && ctxData.CheckAdminToken(token) == nil {
+		return true
+	}
+	if ctxData.ChatRegistry == nil {
+		return false
+	}
+	chat, ok := ctxData.ChatRegistry.Get(chatId)
+	return ok && chat.CreatedBy != "" && chat.CreatedBy == requestTokenId(c)
+}
+
+func apiAddChatMembersHandler(c *fiber.Ctx) error {
+	return apiChangeChatMembersHandler(c, true)
+}
+
+func apiRemoveChatMembersHandler(c *fiber.Ctx) error {
+	return apiChangeChatMembersHandler(c, false)
+}
+
+func apiChangeChatMembersHandler(c *fiber.Ctx, add bool) error {
+	ctxData := extractAppCtxData(c)
+
+	chatId, err := resolveChatParam(ctxData, c.Params("chat"))
+	if err != nil {
+		return deliveryErrorResponse(c, err)
+	}
+	if !mayChangeChatMembers(ctxData, c, chatId) {
+		return sendJsonResponseString(c, fiber.StatusForbidden, "members may be changed only by token which created the chat or by admin token")
+	}
+	var request chatMembersRequest
+	if err := c.BodyParser(&request); err != nil {
+		return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, "unable to parse json")
+	}
+	if len(request.Members) == 0 {
+		return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, "at least one member is required")
+	}
+
+	huids, members := resolveMembers(ctxData, request.Members)
+	response := chatResponse{
+		Result:  "OK",
+		ChatId:  &chatId,
+		Address: chatId.String() + ctxData.GroupChatMailSuffix,
+		Members: members,
+	}
+	if len(huids) == 0 {
+		response.Result = "no members found"
+		return c.Status(fiber.StatusFailedDependency).JSON(response)
+	}
+	if add {
+		err = ctxData.Bot.AddChatMembers(chatId, huids)
+	} else {
+		err = ctxData.Bot.RemoveChatMembers(chatId, huids)
+	}
+	if err != nil {
+		return sendJsonResponseString(c, fiber.StatusServiceUnavailable, err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
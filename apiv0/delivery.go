@@ -0,0 +1,314 @@
+package apiv0
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-botx/botx/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// DeliveryError is returned by Deliver when message can not be delivered.
+// Status is HTTP status code that describes the reason.
+// Muted is set when delivery is blocked by mute.
+type DeliveryError struct {
+	Status  int
+	Message string
+	Muted   *MuteDetails
+}
+
+func (e *DeliveryError) Error() string {
+	return e.Message
+}
+
+func newDeliveryError(status int, message string) *DeliveryError {
+	return &DeliveryError{Status: status, Message: message}
+}
+
+// DeliveryResult describes delivered message.
+type DeliveryResult struct {
+	ChatId       uuid.UUID
+	SyncId       uuid.UUID
+	DryRun       bool
+	AckId        *uuid.UUID
+	EscalationId *uuid.UUID
+	// HeldUntil is set when message is held until quiet hours end
+	HeldUntil *time.Time
+	// DigestAt is set when message is collected for digest of chat
+	DigestAt *time.Time
+	// Members is set when message was sent to alias or topic
+	Members []MemberResult
+	// Queued is set when message is queued until mute is removed
+	Queued *MuteDetails
+	// Muted is set when escalation started although `to` is muted
+	Muted *MuteDetails
+}
+
+// messageOrigin describes who asked to deliver message.
+type messageOrigin struct {
+	// TokenId identifies token of sender, see tokenmanager.TokenId
+	TokenId string
+	// Sender is name of integration that can be muted in chat
+	Sender   string
+	Metadata *messageEncryptedMetadata
+}
+
+// storedPayload is message with its origin stored to be delivered later:
+// in escalation, in queue of muted entry, in digest buffer and with messages held for quiet hours.
+type storedPayload struct {
+	Message  Message                   `json:"message"`
+	TokenId  string                    `json:"token_id"`
+	Sender   string                    `json:"sender,omitempty"`
+	Metadata *messageEncryptedMetadata `json:"metadata,omitempty"`
+}
+
+func marshalStoredPayload(message Message, origin *messageOrigin) (json.RawMessage, error) {
+	return json.Marshal(&storedPayload{
+		Message:  message,
+		TokenId:  origin.TokenId,
+		Sender:   origin.Sender,
+		Metadata: origin.Metadata,
+	})
+}
+
+func (payload *storedPayload) origin() *messageOrigin {
+	return &messageOrigin{
+		TokenId:  payload.TokenId,
+		Sender:   payload.Sender,
+		Metadata: payload.Metadata,
+	}
+}
+
+// Deliver sends message the same way as HTTP API does.
+// It is used by message sources other than HTTP API, sender names the source.
+func Deliver(config *APIConfig, message Message, sender string, requireStatus bool) (DeliveryResult, error) {
+	return deliverMessage(config, message, &messageOrigin{Sender: sender}, requireStatus)
+}
+
+func deliverMessage(ctxData *APIConfig, message Message, origin *messageOrigin, requireStatus bool) (DeliveryResult, error) {
+	if message.OnMuted != "" && message.OnMuted != OnMutedReject && message.OnMuted != OnMutedQueue {
+		return DeliveryResult{}, newDeliveryError(fiber.StatusUnprocessableEntity, "on_muted must be reject or queue")
+	}
+	if message.DryRun {
+		return dryRunMessage(ctxData, message, origin)
+	}
+	result, err := routeMessage(ctxData, message, origin, requireStatus)
+	if err != nil {
+		if queued, ok := queueIfMuted(ctxData, message, origin, err); ok {
+			return queued, nil
+		}
+		return DeliveryResult{}, err
+	}
+	return result, nil
+}
+
+// routeMessage delivers message by escalation, to topic, to alias or to single recipient.
+func routeMessage(ctxData *APIConfig, message Message, origin *messageOrigin, requireStatus bool) (DeliveryResult, error) {
+	if message.Escalation != "" {
+		return deliverWithEscalation(ctxData, message, origin)
+	}
+	if topic, members, ok, err := resolveTopic(ctxData, message.To); ok {
+		if err != nil {
+			return DeliveryResult{}, err
+		}
+		return deliverToMembers(ctxData, message, topic, members, origin, requireStatus)
+	}
+	if alias, members, ok := resolveAlias(ctxData, message.To); ok {
+		return deliverToMembers(ctxData, message, alias, members, origin, requireStatus)
+	}
+	return deliverSingle(ctxData, message, origin, requireStatus)
+}
+
+func deliverSingle(ctxData *APIConfig, message Message, origin *messageOrigin, requireStatus bool) (DeliveryResult, error) {
+	chatId, ndOpts, err := prepareSingle(ctxData, message, origin, chatWithUser)
+	if err != nil {
+		return DeliveryResult{}, err
+	}
+
+	heldUntil, err := holdForQuietHours(ctxData, message, origin, chatId)
+	if err != nil {
+		return DeliveryResult{}, err
+	}
+	if heldUntil != nil {
+		return DeliveryResult{ChatId: chatId, HeldUntil: heldUntil}, nil
+	}
+
+	digestAt, err := addToDigest(ctxData, message, origin, chatId)
+	if err != nil {
+		return DeliveryResult{}, err
+	}
+	if digestAt != nil {
+		recordSender(ctxData, chatId, origin.Sender)
+		return DeliveryResult{ChatId: chatId, DigestAt: digestAt}, nil
+	}
+
+	var result DeliveryResult
+	if message.AckRequired && ctxData.AckManager != nil {
+		result, err = deliverWithAck(ctxData, message, chatId, ndOpts, requireStatus)
+		if err != nil {
+			return DeliveryResult{}, err
+		}
+	} else {
+		syncId, err := sendToChat(ctxData, chatId, message.Body, ndOpts, requireStatus)
+		if err != nil {
+			return DeliveryResult{}, err
+		}
+		result = DeliveryResult{ChatId: chatId, SyncId: syncId}
+	}
+	recordThread(ctxData, message, origin, result)
+	recordSender(ctxData, chatId, origin.Sender)
+	return result, nil
+}
+
+// prepareSingle resolves recipient chat and builds message options.
+func prepareSingle(ctxData *APIConfig, message Message, origin *messageOrigin, userChat userChatFunc) (uuid.UUID, []models.NDRequestOption, error) {
+	chatId, err := resolveRecipientWith(ctxData, message.To, userChat)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	err = checkAllowedSender(ctxData, chatId, origin.Sender)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	// Create Message
+
+	ndOpts, err := buildButtonOptions(ctxData, message, origin)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	if origin.Metadata != nil {
+		ndOpts = append(ndOpts, models.WithNDMetadata(origin.Metadata))
+	}
+
+	if replyOpt, ok := replyOption(ctxData, message, origin, chatId); ok {
+		ndOpts = append(ndOpts, replyOpt)
+	}
+	return chatId, ndOpts, nil
+}
+
+// userChatFunc returns personal chat with user found by addr.
+type userChatFunc func(ctxData *APIConfig, addr string) (uuid.UUID, error)
+
+// resolveRecipient finds chat for recipient address and checks that sending to it is allowed.
+func resolveRecipient(ctxData *APIConfig, to string) (uuid.UUID, error) {
+	return resolveRecipientWith(ctxData, to, chatWithUser)
+}
+
+// resolveRecipientWith is resolveRecipient which gets personal chats with users by userChat.
+func resolveRecipientWith(ctxData *APIConfig, to string, userChat userChatFunc) (uuid.UUID, error) {
+	addr, isUserId, err := parseRecipientAddress(to)
+	if err != nil {
+		return uuid.Nil, newDeliveryError(fiber.StatusUnprocessableEntity, err.Error())
+	}
+
+	err = checkAllowedAddress(ctxData, addr)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if isUserId {
+		chatId, err := userChat(ctxData, addr)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		return chatId, checkAllowedChat(ctxData, chatId)
+	}
+
+	onCallAddr, err := resolveRotation(ctxData, addr)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if onCallAddr != addr {
+		addr = onCallAddr
+		err = checkAllowedAddress(ctxData, addr)
+		if err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	var chatId uuid.UUID
+	// Decide if it is UUID-Group-like or not:
+	addrPrefix, ok := strings.CutSuffix(addr, ctxData.GroupChatMailSuffix)
+	aliasName, isChatAlias := cutChatAliasSuffix(ctxData, addr)
+	if isChatAlias {
+		chatId, ok = ctxData.ChatAliasManager.Resolve(aliasName)
+		if !ok {
+			return uuid.Nil, newDeliveryError(fiber.StatusNotFound, "chat alias not found")
+		}
+	} else if !ok {
+		chatId, err = userChat(ctxData, addr)
+		if err != nil {
+			return uuid.Nil, err
+		}
+	} else {
+		chatId, err = uuid.Parse(addrPrefix)
+		if err != nil || chatId.String() != addrPrefix {
+			return uuid.Nil, newDeliveryError(fiber.StatusUnprocessableEntity, fmt.Sprintf("chat_id '%s' in address '%s' is not recognized as UUID", addrPrefix, addr))
+		}
+	}
+
+	return chatId, checkAllowedChat(ctxData, chatId)
+}
+
+func cutChatAliasSuffix(ctxData *APIConfig, addr string) (string, bool) {
+	if ctxData.ChatAliasManager == nil || ctxData.ChatAliasMailSuffix == "" {
+		return "", false
+	}
+	return strings.CutSuffix(addr, ctxData.ChatAliasMailSuffix)
+}
+
+// newNDRequest builds message with mentions expanded.
+func newNDRequest(ctxData *APIConfig, chatId uuid.UUID, body string, ndOpts []models.NDRequestOption) (*models.NDRequest, error) {
+	body, mentionOpts := ExpandMentions(ctxData, body)
+	return models.NewNDRequest(chatId, body, append(ndOpts, mentionOpts...)...)
+}
+
+func sendToChat(ctxData *APIConfig, chatId uuid.UUID, body string, ndOpts []models.NDRequestOption, requireStatus bool) (uuid.UUID, error) {
+	b := ctxData.Bot
+
+	ndr, err := newNDRequest(ctxData, chatId, body, ndOpts)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var syncId uuid.UUID
+	if !requireStatus {
+		syncId, err = b.SendMessageAsync(ndr)
+	} else {
+		syncId, err = b.SendMessageSync(ndr)
+	}
+	if err != nil {
+		return uuid.Nil, newDeliveryError(fiber.StatusServiceUnavailable, err.Error())
+	}
+	return syncId, nil
+}
+
+// deliveryErrorStatus returns HTTP status code describing err.
+func deliveryErrorStatus(err error) int {
+	var deliveryErr *DeliveryError
+	if errors.As(err, &deliveryErr) {
+		return deliveryErr.Status
+	}
+	return fiber.StatusInternalServerError
+}
+
+func deliveryErrorResponse(c *fiber.Ctx, err error) error {
+	var deliveryErr *DeliveryError
+	if errors.As(err, &deliveryErr) {
+		if deliveryErr.Muted != nil {
+			return c.Status(deliveryErr.Status).JSON(mutedResponse{
+				Result:      deliveryErr.Message,
+				Code:        "muted",
+				MuteDetails: deliveryErr.Muted,
+			})
+		}
+		return sendJsonResponseString(c, deliveryErr.Status, deliveryErr.Message)
+	}
+	return err
+}
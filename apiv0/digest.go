@@ -0,0 +1,174 @@
+package apiv0
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sendyxmail/digestmanager"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-botx/botx/models"
+	"github.com/google/uuid"
+)
+
+// addToDigest collects message for digest when digest mode of chat is on.
+// Messages with high priority and messages that require acknowledgement are never collected.
+func addToDigest(ctxData *APIConfig, message Message, origin *messageOrigin, chatId uuid.UUID) (*time.Time, error) {
+	if !mayCollectForDigest(ctxData, message) {
+		return nil, nil
+	}
+	payload, err := marshalStoredPayload(message, origin)
+	if err != nil {
+		return nil, err
+	}
+	added, sendAt, err := ctxData.DigestManager.Add(chatId, payload)
+	if err != nil || !added {
+		return nil, err
+	}
+	return &sendAt, nil
+}
+
+func mayCollectForDigest(ctxData *APIConfig, message Message) bool {
+	return ctxData.DigestManager != nil && message.Priority != PriorityHigh && !message.AckRequired
+}
+
+// predictDigest returns when digest is sent if message would be collected now.
+func predictDigest(ctxData *APIConfig, message Message, chatId uuid.UUID) *time.Time {
+	if !mayCollectForDigest(ctxData, message) {
+		return nil
+	}
+	collected, sendAt := ctxData.DigestManager.NextDigest(chatId)
+	if !collected {
+		return nil
+	}
+	return &sendAt
+}
+
+// DeliverDigest sends collected messages as one message with buttons of all messages.
+// If chat was muted meanwhile, messages with on_muted "queue" are queued until unmute and the rest are dropped.
+// Messages of muted senders are skipped.
+func DeliverDigest(config *APIConfig, chatId uuid.UUID, messages []digestmanager.Message) error {
+	collected := make([]collectedMessage, 0, len(messages))
+	for _, message := range messages {
+		collected = append(collected, collectedMessage{Payload: message.Payload, ReceivedAt: message.ReceivedAt})
+	}
+	if err := checkAllowedChat(config, chatId); err != nil {
+		queued := queueCollected(config, chatId, collected, err)
+		log.Printf("digest to %s is muted, queued %d and dropped %d messages: %s", chatId, queued, len(collected)-queued, err.Error())
+		return nil
+	}
+	parts := buildCollected(config, chatId, collected, time.TimeOnly)
+	return sendCollected(config, chatId, "📋 Digest / Сводка: %d", parts)
+}
+
+// collectedMessage is a message sent later together with other messages.
+type collectedMessage struct {
+	Payload    json.RawMessage
+	ReceivedAt time.Time
+}
+
+// queueCollected queues collected messages with on_muted "queue" when chat was muted before they were sent.
+// It returns number of queued messages.
+func queueCollected(config *APIConfig, chatId uuid.UUID, messages []collectedMessage, mutedErr error) int {
+	queued := 0
+	for _, collected := range messages {
+		var payload storedPayload
+		err := json.Unmarshal(collected.Payload, &payload)
+		if err != nil {
+			log.Printf("skipping broken collected message to %s: %s", chatId, err.Error())
+			continue
+		}
+		if _, ok := queueIfMuted(config, payload.Message, payload.origin(), mutedErr); ok {
+			queued++
+		}
+	}
+	return queued
+}
+
+// Limits of one message with collected messages, more messages are sent in several parts
+const (
+	maxCollectedParts  = 50
+	maxCollectedLength = 4000
+)
+
+// collectedPart is text and buttons of one collected message.
+type collectedPart struct {
+	Text       string
+	ButtonOpts []models.NDRequestOption
+}
+
+// buildCollected builds parts of message from collected messages with their buttons.
+// Messages of senders muted in chat are skipped.
+func buildCollected(config *APIConfig, chatId uuid.UUID, messages []collectedMessage, timeLayout string) []collectedPart {
+	parts := []collectedPart{}
+	for _, collected := range messages {
+		var payload storedPayload
+		err := json.Unmarshal(collected.Payload, &payload)
+		if err != nil {
+			log.Printf("skipping broken collected message to %s: %s", chatId, err.Error())
+			continue
+		}
+		if checkAllowedSender(config, chatId, payload.Sender) != nil {
+			continue
+		}
+		buttonOpts, err := buildButtonOptions(config, payload.Message, payload.origin())
+		if err != nil {
+			log.Printf("skipping buttons of collected message to %s: %s", chatId, err.Error())
+		}
+		parts = append(parts, collectedPart{
+			Text:       fmt.Sprintf("**%s**\n%s", collected.ReceivedAt.Local().Format(timeLayout), payload.Message.Body),
+			ButtonOpts: buttonOpts,
+		})
+	}
+	return parts
+}
+
+// sendCollected sends parts with title formatted with number of parts.
+// Parts are split into several messages by maxCollectedParts and maxCollectedLength.
+// Error is returned only if nothing was sent, so that collected messages are retried without duplicates.
+func sendCollected(config *APIConfig, chatId uuid.UUID, title string, parts []collectedPart) error {
+	chunks := splitCollected(parts)
+	for idx, chunk := range chunks {
+		header := fmt.Sprintf(title, len(parts))
+		if len(chunks) > 1 {
+			header += fmt.Sprintf(" (%d/%d)", idx+1, len(chunks))
+		}
+		texts := []string{header}
+		ndOpts := []models.NDRequestOption{}
+		for _, part := range chunk {
+			texts = append(texts, part.Text)
+			ndOpts = append(ndOpts, part.ButtonOpts...)
+		}
+		_, err := sendToChat(config, chatId, strings.Join(texts, "\n\n"), ndOpts, true)
+		if err != nil {
+			if idx == 0 {
+				return err
+			}
+			log.Printf("dropping part %d of %d of collected messages to %s: %s", idx+1, len(chunks), chatId, err.Error())
+		}
+	}
+	return nil
+}
+
+// splitCollected groups parts into messages. Part longer than maxCollectedLength is sent alone.
+func splitCollected(parts []collectedPart) [][]collectedPart {
+	chunks := [][]collectedPart{}
+	chunk := []collectedPart{}
+	length := 0
+	for _, part := range parts {
+		partLength := utf8.RuneCountInString(part.Text)
+		if len(chunk) > 0 && (len(chunk) == maxCollectedParts || length+partLength > maxCollectedLength) {
+			chunks = append(chunks, chunk)
+			chunk = []collectedPart{}
+			length = 0
+		}
+		chunk = append(chunk, part)
+		length += partLength
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
@@ -0,0 +1,117 @@
+package apiv0
+
+import (
+	"encoding/json"
+	"errors"
+	"sendyxmail/ackmanager"
+	"sendyxmail/escalationmanager"
+
+	"github.com/go-botx/botx/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// deliverWithEscalation sends message to `to` and then to policy steps until it is acknowledged.
+// If `to` is muted, message with on_muted "queue" is queued and escalation starts after unmute.
+// Otherwise mute of `to` is reported in result and the next step is executed immediately.
+func deliverWithEscalation(ctxData *APIConfig, message Message, origin *messageOrigin) (DeliveryResult, error) {
+	em := ctxData.EscalationManager
+	if em == nil || ctxData.AckManager == nil {
+		return DeliveryResult{}, newDeliveryError(fiber.StatusNotImplemented, "escalations are not configured")
+	}
+	if !em.HasPolicy(message.Escalation) {
+		return DeliveryResult{}, newDeliveryError(fiber.StatusUnprocessableEntity, "unknown escalation policy")
+	}
+	var muted *MuteDetails
+	if message.To != "" {
+		if _, _, err := parseRecipientAddress(message.To); err != nil {
+			return DeliveryResult{}, newDeliveryError(fiber.StatusUnprocessableEntity, err.Error())
+		}
+		// Other errors of `to` are handled by escalation step
+		_, err := resolveRecipientWith(ctxData, message.To, knownChatWithUser)
+		if queued, ok := queueIfMuted(ctxData, message, origin, err); ok {
+			return queued, nil
+		}
+		muted, _ = mutedDetails(err)
+	}
+	payload, err := marshalStoredPayload(message, origin)
+	if err != nil {
+		return DeliveryResult{}, err
+	}
+	ack, err := ctxData.AckManager.Create(message.To, "", message.Body, 0)
+	if err != nil {
+		return DeliveryResult{}, err
+	}
+	escalation, err := em.Start(message.Escalation, message.To, ack.Id, payload)
+	if err != nil {
+		return DeliveryResult{}, err
+	}
+	return DeliveryResult{AckId: &ack.Id, EscalationId: &escalation.Id, Muted: muted}, nil
+}
+
+// EscalationStep returns function which delivers escalated message to the recipient of one step.
+func EscalationStep(config *APIConfig) escalationmanager.StepFunc {
+	return func(escalation escalationmanager.Escalation, to string) (string, error) {
+		var payload storedPayload
+		err := json.Unmarshal(escalation.Payload, &payload)
+		if err != nil {
+			return escalationmanager.OutcomeFailed, err
+		}
+		chatId, err := resolveRecipient(config, to)
+		if err != nil {
+			return escalationOutcome(err), err
+		}
+		origin := payload.origin()
+		err = checkAllowedSender(config, chatId, origin.Sender)
+		if err != nil {
+			return escalationOutcome(err), err
+		}
+		ndOpts, err := buildButtonOptions(config, payload.Message, origin)
+		if err != nil {
+			return escalationmanager.OutcomeFailed, err
+		}
+		ndOpts = append(ndOpts, ackButtonOption(escalation.AckId))
+		if origin.Metadata != nil {
+			ndOpts = append(ndOpts, models.WithNDMetadata(origin.Metadata))
+		}
+		syncId, err := sendToChat(config, chatId, payload.Message.Body, ndOpts, true)
+		if err != nil {
+			return escalationmanager.OutcomeFailed, err
+		}
+		recordSender(config, chatId, origin.Sender)
+		err = config.AckManager.AddMessage(escalation.AckId, ackmanager.SentMessage{ChatId: chatId, SyncId: syncId})
+		if err != nil {
+			return escalationmanager.OutcomeSent, err
+		}
+		return escalationmanager.OutcomeSent, nil
+	}
+}
+
+func escalationOutcome(err error) string {
+	var deliveryErr *DeliveryError
+	if errors.As(err, &deliveryErr) {
+		switch deliveryErr.Status {
+		case fiber.StatusNotFound:
+			return escalationmanager.OutcomeNotFound
+		case fiber.StatusUnavailableForLegalReasons:
+			return escalationmanager.OutcomeMuted
+		}
+	}
+	return escalationmanager.OutcomeFailed
+}
+
+func apiGetEscalationHandler(c *fiber.Ctx) error {
+	ctxData := extractAppCtxData(c)
+	if ctxData.EscalationManager == nil {
+		return sendJsonResponseString(c, fiber.StatusNotImplemented, "escalations are not configured")
+	}
+	escalationId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, "escalation id is not UUID")
+	}
+	escalation, ok := ctxData.EscalationManager.Get(escalationId)
+	if !ok {
+		return sendJsonResponseString(c, fiber.StatusNotFound, "escalation not found")
+	}
+	return c.Status(fiber.StatusOK).JSON(escalation)
+}
@@ -0,0 +1,66 @@
+package apiv0
+
+import (
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/go-botx/botx/models"
+	"github.com/google/uuid"
+)
+
+// ChatMentionPrefix marks chat mention in message body: @{chat:<chat_id>}
+const ChatMentionPrefix = "chat:"
+
+var mentionPattern = regexp.MustCompile(`@\{([^{}\s]+)\}`)
+
+// ExpandMentions replaces @{<user address>} and @{chat:<chat_id>} in body with BotX mentions.
+// Mentions which can not be resolved are replaced with plain address.
+func ExpandMentions(config *APIConfig, body string) (string, []models.NDRequestOption) {
+	mentions := []models.NDMention{}
+	embeds := map[string]string{}
+	body = mentionPattern.ReplaceAllStringFunc(body, func(match string) string {
+		target := mentionPattern.FindStringSubmatch(match)[1]
+		if embed, ok := embeds[target]; ok {
+			return embed
+		}
+		mention, ok := resolveMention(config, target)
+		if !ok {
+			embeds[target] = target
+			return target
+		}
+		mentions = append(mentions, mention)
+		embeds[target] = mention.Embed()
+		return embeds[target]
+	})
+	if len(mentions) == 0 {
+		return body, nil
+	}
+	return body, []models.NDRequestOption{models.WithNDMentions(mentions...)}
+}
+
+func resolveMention(config *APIConfig, target string) (models.NDMention, bool) {
+	if len(target) > len(ChatMentionPrefix) && strings.EqualFold(target[:len(ChatMentionPrefix)], ChatMentionPrefix) {
+		chatId, err := uuid.Parse(target[len(ChatMentionPrefix):])
+		if err != nil {
+			return models.NDMention{}, false
+		}
+		name := ""
+		if config.ChatRegistry != nil {
+			if chat, ok := config.ChatRegistry.Get(chatId); ok {
+				name = chat.Name
+			}
+		}
+		return models.NewChatMention(chatId, name), true
+	}
+	addr, _, err := parseRecipientAddress(target)
+	if err != nil {
+		return models.NDMention{}, false
+	}
+	user, err := findSingleUser(config, addr)
+	if err != nil {
+		log.Printf("unable to mention '%s': %s", addr, err.Error())
+		return models.NDMention{}, false
+	}
+	return models.NewUserMention(user.UserHuid, user.Name), true
+}
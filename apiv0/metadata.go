@@ -6,6 +6,7 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"hash/adler32"
 
 	"github.com/gofiber/fiber/v2"
@@ -26,10 +27,8 @@ type messageEncryptedMetadata struct {
 
 func storeEncryptedMetadataInCtx(c *fiber.Ctx, aesKey [32]byte) error {
 	tokenString := extractBearerToken(c.Get(fiber.HeaderAuthorization, ""))
-	hash := adler32.New()
-	hash.Write([]byte(tokenString))
 	metadata := messageMetadata{
-		TokenAdler32: hash.Sum32(),
+		TokenAdler32: adler32.Checksum([]byte(tokenString)),
 		CallerAddr:   c.IP(),
 		CallerAddrs:  c.IPs(),
 	}
@@ -37,26 +36,54 @@ func storeEncryptedMetadataInCtx(c *fiber.Ctx, aesKey [32]byte) error {
 	if err != nil {
 		return err
 	}
-	block, err := aes.NewCipher(aesKey[:])
+	ciphertext, err := encryptPayload(aesKey, metadataBytes)
 	if err != nil {
 		return err
 	}
+	encryptedMetadata := &messageEncryptedMetadata{
+		EncryptedMetadata: ciphertext,
+	}
+	c.Locals(apiMetadataKey, encryptedMetadata)
+	return nil
+}
+
+func encryptPayload(aesKey [32]byte, payload []byte) (string, error) {
+	block, err := aes.NewCipher(aesKey[:])
+	if err != nil {
+		return "", err
+	}
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	nonce := make([]byte, gcm.NonceSize())
 	if _, err = rand.Read(nonce); err != nil {
-		return err
+		return "", err
 	}
 
-	ciphertext := gcm.Seal(nonce, nonce, metadataBytes, nil)
-	encryptedMetadata := &messageEncryptedMetadata{
-		EncryptedMetadata: base64.StdEncoding.EncodeToString(ciphertext),
+	ciphertext := gcm.Seal(nonce, nonce, payload, nil)
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+func decryptPayload(aesKey [32]byte, payload string) ([]byte, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, err
 	}
-	c.Locals(apiMetadataKey, encryptedMetadata)
-	return nil
+	block, err := aes.NewCipher(aesKey[:])
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("encrypted payload is too short")
+	}
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
 }
 
 func loadEncryptedMetadataFromCtx(c *fiber.Ctx) *messageEncryptedMetadata {
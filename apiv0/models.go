@@ -1,9 +1,18 @@
 package apiv0
 
 type Message struct {
-	To      string      `json:"to"`
-	Body    string      `json:"body"`
-	Buttons []ButtonRow `json:"buttons"`
+	To          string      `json:"to"`
+	Body        string      `json:"body"`
+	Buttons     []ButtonRow `json:"buttons"`
+	AckRequired bool        `json:"ack_required,omitempty"`
+	AckTimeout  int         `json:"ack_timeout,omitempty"`
+	AckFallback string      `json:"ack_fallback,omitempty"`
+	Escalation  string      `json:"escalation,omitempty"`
+	DryRun      bool        `json:"dry_run,omitempty"`
+	Key         string      `json:"key,omitempty"`
+	ReplyTo     string      `json:"reply_to,omitempty"`
+	Priority    string      `json:"priority,omitempty"`
+	OnMuted     string      `json:"on_muted,omitempty"`
 }
 
 type ButtonRow []Button
@@ -11,6 +20,8 @@ type ButtonRow []Button
 type Button struct {
 	Label           string `json:"label"`
 	Link            string `json:"link"`
+	Command         string `json:"command,omitempty"`
+	Data            any    `json:"data,omitempty"`
 	TextColor       string `json:"text_color,omitempty"`
 	BackgroundColor string `json:"background_color,omitempty"`
 	TextAlign       string `json:"text_align,omitempty"`
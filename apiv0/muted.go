@@ -0,0 +1,154 @@
+package apiv0
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sendyxmail/mutemanager"
+	"sendyxmail/queuemanager"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Values of Message.OnMuted
+const (
+	OnMutedReject = "reject"
+	OnMutedQueue  = "queue"
+)
+
+// Scopes of mute that blocked delivery
+const (
+	MuteScopeAddress = "address"
+	MuteScopeChat    = "chat"
+	MuteScopeSender  = "sender"
+)
+
+// MuteDetails describes mute that blocked delivery.
+type MuteDetails struct {
+	Scope string `json:"scope"`
+	// Entry is muted entry as it is shown by admin mute API
+	Entry      string     `json:"entry,omitempty"`
+	Address    string     `json:"address,omitempty"`
+	ChatId     *uuid.UUID `json:"chat_id,omitempty"`
+	Sender     string     `json:"sender,omitempty"`
+	MutedUntil *time.Time `json:"muted_until,omitempty"`
+	// MutedBySender is true if only sender of message is muted in chat
+	MutedBySender bool `json:"muted_by_sender"`
+}
+
+type mutedResponse struct {
+	Result string `json:"result"`
+	Code   string `json:"code"`
+	Queued bool   `json:"queued,omitempty"`
+	*MuteDetails
+}
+
+// newMutedError converts error of mute check to delivery error with mute details.
+func newMutedError(err error, details MuteDetails) *DeliveryError {
+	var mutedErr *mutemanager.MutedError
+	if errors.As(err, &mutedErr) {
+		details.Entry = mutedErr.Entry
+		if !mutedErr.Until.IsZero() {
+			until := mutedErr.Until.UTC()
+			details.MutedUntil = &until
+		}
+	}
+	deliveryErr := newDeliveryError(fiber.StatusUnavailableForLegalReasons, err.Error())
+	deliveryErr.Muted = &details
+	return deliveryErr
+}
+
+// checkAllowedAddress checks that address is not muted.
+func checkAllowedAddress(ctxData *APIConfig, addr string) error {
+	if ctxData.CheckAllowedSend == nil {
+		return nil
+	}
+	err := ctxData.CheckAllowedSend(addr)
+	if err != nil {
+		return newMutedError(err, MuteDetails{Scope: MuteScopeAddress, Address: addr})
+	}
+	return nil
+}
+
+// checkAllowedChat checks that chat is not muted.
+func checkAllowedChat(ctxData *APIConfig, chatId uuid.UUID) error {
+	if ctxData.CheckAllowedSend == nil {
+		return nil
+	}
+	err := ctxData.CheckAllowedSend(chatId.String())
+	if err != nil {
+		return newMutedError(err, MuteDetails{Scope: MuteScopeChat, ChatId: &chatId})
+	}
+	return nil
+}
+
+// checkAllowedSender checks that sender is not muted in chat.
+func checkAllowedSender(ctxData *APIConfig, chatId uuid.UUID, sender string) error {
+	if ctxData.CheckAllowedSender == nil || sender == "" {
+		return nil
+	}
+	err := ctxData.CheckAllowedSender(chatId, sender)
+	if err != nil {
+		return newMutedError(err, MuteDetails{Scope: MuteScopeSender, ChatId: &chatId, Sender: sender, MutedBySender: true})
+	}
+	return nil
+}
+
+// mutedDetails returns details of mute if err is caused by mute.
+func mutedDetails(err error) (*MuteDetails, bool) {
+	var deliveryErr *DeliveryError
+	if errors.As(err, &deliveryErr) && deliveryErr.Muted != nil {
+		return deliveryErr.Muted, true
+	}
+	return nil, false
+}
+
+// mayQueue returns details of mute if message would be queued because of err.
+func mayQueue(ctxData *APIConfig, message Message, err error) (*MuteDetails, bool) {
+	details, muted := mutedDetails(err)
+	if !muted || message.OnMuted != OnMutedQueue || ctxData.QueueManager == nil || details.Entry == "" {
+		return nil, false
+	}
+	return details, true
+}
+
+// queueIfMuted queues message with on_muted "queue" when err is caused by mute.
+// It returns false if message was not queued and err must be returned to client.
+func queueIfMuted(ctxData *APIConfig, message Message, origin *messageOrigin, err error) (DeliveryResult, bool) {
+	details, ok := mayQueue(ctxData, message, err)
+	if !ok {
+		return DeliveryResult{}, false
+	}
+	payload, err := marshalStoredPayload(message, origin)
+	if err != nil {
+		return DeliveryResult{}, false
+	}
+	_, err = ctxData.QueueManager.Add(details.Entry, payload)
+	if err != nil {
+		log.Printf("failed to queue message to muted %s: %s", details.Entry, err.Error())
+		return DeliveryResult{}, false
+	}
+	return DeliveryResult{Queued: details}, true
+}
+
+// DeliverQueued delivers message queued until entry was unmuted.
+// Message is queued again if it is muted by another entry.
+// Error is returned only if delivery should be retried later.
+func DeliverQueued(config *APIConfig, entry string, queued queuemanager.Message) error {
+	var payload storedPayload
+	err := json.Unmarshal(queued.Payload, &payload)
+	if err != nil {
+		log.Printf("skipping broken queued message of %s: %s", entry, err.Error())
+		return nil
+	}
+	_, err = deliverMessage(config, payload.Message, payload.origin(), true)
+	if err != nil {
+		if deliveryErrorStatus(err) == fiber.StatusServiceUnavailable {
+			return err
+		}
+		log.Printf("dropping queued message of %s to %s: %s", entry, payload.Message.To, err.Error())
+	}
+	return nil
+}
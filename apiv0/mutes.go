@@ -0,0 +1,318 @@
+package apiv0
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sendyxmail/mutemanager"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const defaultMuteHistoryLimit = 100
+
+// muteCSVHeader is the header of exported CSV. Import requires entry column, until and reason are optional.
+var muteCSVHeader = []string{"entry", "until", "muted_at", "source", "actor_huid", "actor_name", "reason"}
+
+// MuteItem is muted entry with details of the latest mute, if they are known.
+type MuteItem struct {
+	Entry     string     `json:"entry"`
+	Until     *time.Time `json:"until,omitempty"`
+	MutedAt   *time.Time `json:"muted_at,omitempty"`
+	Source    string     `json:"source,omitempty"`
+	ActorHuid string     `json:"actor_huid,omitempty"`
+	ActorName string     `json:"actor_name,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+}
+
+type muteRequest struct {
+	Until  *time.Time `json:"until"`
+	Reason string     `json:"reason"`
+}
+
+// apiAdminListMutesHandler returns all muted entries as JSON or as CSV with format=csv.
+func apiAdminListMutesHandler(c *fiber.Ctx) error {
+	ctxData := extractAppCtxData(c)
+	if ctxData.MuteManager == nil {
+		return sendJsonResponseString(c, fiber.StatusNotImplemented, "mute manager is not configured")
+	}
+	items, err := listMutes(ctxData.MuteManager)
+	if err != nil {
+		return sendJsonResponseString(c, fiber.StatusInternalServerError, err.Error())
+	}
+	switch c.Query("format", "json") {
+	case "json":
+		return c.Status(fiber.StatusOK).JSON(items)
+	case "csv":
+		payload, err := writeMutesCSV(items)
+		if err != nil {
+			return sendJsonResponseString(c, fiber.StatusInternalServerError, err.Error())
+		}
+		c.Attachment("mutes.csv")
+		c.Response().Header.SetContentType("text/csv; charset=utf-8")
+		return c.Status(fiber.StatusOK).Send(payload)
+	}
+	return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, "format must be json or csv")
+}
+
+// apiAdminMuteHandler mutes entry from URL until time given in optional body.
+func apiAdminMuteHandler(c *fiber.Ctx) error {
+	ctxData := extractAppCtxData(c)
+	if ctxData.MuteManager == nil {
+		return sendJsonResponseString(c, fiber.StatusNotImplemented, "mute manager is not configured")
+	}
+	entry, err := muteEntryParam(ctxData, c)
+	if err != nil {
+		return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, err.Error())
+	}
+	var request muteRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, "unable to parse json")
+		}
+	}
+	until := time.Time{}
+	if request.Until != nil {
+		until = *request.Until
+		if !until.After(time.Now()) {
+			return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, "until must be in the future")
+		}
+	}
+	changed, err := ctxData.MuteManager.SetMuteUntil(entry, until, adminMuteOrigin(ctxData, c, request.Reason))
+	if err != nil {
+		return sendJsonResponseString(c, fiber.StatusInternalServerError, err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"result":  "OK",
+		"entry":   entry,
+		"changed": changed,
+	})
+}
+
+// apiAdminUnmuteHandler unmutes entry from URL. Reason may be given in reason query parameter.
+func apiAdminUnmuteHandler(c *fiber.Ctx) error {
+	ctxData := extractAppCtxData(c)
+	if ctxData.MuteManager == nil {
+		return sendJsonResponseString(c, fiber.StatusNotImplemented, "mute manager is not configured")
+	}
+	entry, err := muteEntryParam(ctxData, c)
+	if err != nil {
+		return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, err.Error())
+	}
+	changed, err := ctxData.MuteManager.SetMute(entry, false, adminMuteOrigin(ctxData, c, c.Query("reason")))
+	if err != nil {
+		return sendJsonResponseString(c, fiber.StatusInternalServerError, err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"result":  "OK",
+		"entry":   entry,
+		"changed": changed,
+	})
+}
+
+// apiAdminImportMutesHandler mutes all entries from JSON array or CSV body.
+// Nothing is muted if any entry is invalid. Entries missing in body are not unmuted.
+func apiAdminImportMutesHandler(c *fiber.Ctx) error {
+	ctxData := extractAppCtxData(c)
+	if ctxData.MuteManager == nil {
+		return sendJsonResponseString(c, fiber.StatusNotImplemented, "mute manager is not configured")
+	}
+	var items []MuteItem
+	var err error
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), "text/csv") {
+		items, err = readMutesCSV(bytes.NewReader(c.Body()))
+	} else {
+		err = json.Unmarshal(c.Body(), &items)
+	}
+	if err != nil {
+		return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, fmt.Sprintf("unable to parse mutes: %s", err.Error()))
+	}
+	now := time.Now()
+	for i := range items {
+		items[i].Entry, err = normalizeMuteEntry(ctxData, items[i].Entry)
+		if err != nil {
+			return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, fmt.Sprintf("item %d: %s", i+1, err.Error()))
+		}
+		if items[i].Until != nil && !items[i].Until.After(now) {
+			return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, fmt.Sprintf("item %d: until must be in the future", i+1))
+		}
+	}
+	muted := 0
+	for _, item := range items {
+		until := time.Time{}
+		if item.Until != nil {
+			until = *item.Until
+		}
+		changed, err := ctxData.MuteManager.SetMuteUntil(item.Entry, until, adminMuteOrigin(ctxData, c, item.Reason))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"result": fmt.Sprintf("failed to mute %s: %s", item.Entry, err.Error()),
+				"muted":  muted,
+			})
+		}
+		if changed {
+			muted++
+		}
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"result":    "OK",
+		"muted":     muted,
+		"unchanged": len(items) - muted,
+	})
+}
+
+// apiAdminMuteHistoryHandler returns latest mute changes, optionally of one entry given in entry query parameter.
+func apiAdminMuteHistoryHandler(c *fiber.Ctx) error {
+	ctxData := extractAppCtxData(c)
+	if ctxData.MuteManager == nil {
+		return sendJsonResponseString(c, fiber.StatusNotImplemented, "mute manager is not configured")
+	}
+	limit := c.QueryInt("limit", defaultMuteHistoryLimit)
+	if limit <= 0 {
+		return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, "limit must be positive")
+	}
+	events, err := ctxData.MuteManager.History(c.Query("entry"), limit)
+	if err != nil {
+		return sendJsonResponseString(c, fiber.StatusInternalServerError, err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(events)
+}
+
+// listMutes returns muted entries sorted by entry with details of their latest mute events.
+func listMutes(mm *mutemanager.MuteManager) ([]MuteItem, error) {
+	entries := mm.Entries()
+	latest, err := mm.LastEvents()
+	if err != nil {
+		return nil, err
+	}
+	items := []MuteItem{}
+	for entry, until := range entries {
+		item := MuteItem{Entry: entry}
+		if !until.IsZero() {
+			item.Until = &until
+		}
+		if event, ok := latest[entry]; ok && event.Muted {
+			item.MutedAt = &event.Time
+			item.Source = event.Source
+			item.ActorHuid = event.ActorHuid
+			item.ActorName = event.ActorName
+			item.Reason = event.Reason
+		}
+		items = append(items, item)
+	}
+	slices.SortFunc(items, func(a, b MuteItem) int {
+		return strings.Compare(a.Entry, b.Entry)
+	})
+	return items, nil
+}
+
+func adminMuteOrigin(ctxData *APIConfig, c *fiber.Ctx, reason string) mutemanager.Origin {
+	tokenId := requestTokenId(c)
+	return mutemanager.Origin{
+		Source:    mutemanager.SourceAPI,
+		ActorName: senderName(ctxData, tokenId),
+		Reason:    strings.TrimSpace(reason),
+	}
+}
+
+func muteEntryParam(ctxData *APIConfig, c *fiber.Ctx) (string, error) {
+	entry, err := url.PathUnescape(c.Params("entry"))
+	if err != nil {
+		return "", errors.New("unable to decode entry")
+	}
+	return normalizeMuteEntry(ctxData, entry)
+}
+
+// normalizeMuteEntry converts chat ID, chat address, user address or "<chat ID>/<sender>"
+// to the form checked on delivery.
+func normalizeMuteEntry(ctxData *APIConfig, entry string) (string, error) {
+	entry = strings.TrimSpace(entry)
+	if chat, sender, ok := strings.Cut(entry, "/"); ok {
+		chatId, err := uuid.Parse(chat)
+		if err != nil || sender == "" {
+			return "", fmt.Errorf("entry '%s' must look like <chat id>/<sender>", entry)
+		}
+		return mutemanager.SenderEntry(chatId.String(), sender), nil
+	}
+	if chatId, err := uuid.Parse(entry); err == nil {
+		return chatId.String(), nil
+	}
+	addr, _, err := parseRecipientAddress(entry)
+	if err != nil {
+		return "", fmt.Errorf("entry '%s': %w", entry, err)
+	}
+	if chat, ok := strings.CutSuffix(addr, ctxData.GroupChatMailSuffix); ok {
+		if chatId, err := uuid.Parse(chat); err == nil {
+			return chatId.String(), nil
+		}
+	}
+	return addr, nil
+}
+
+func writeMutesCSV(items []MuteItem) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	writer := csv.NewWriter(buffer)
+	writer.Write(muteCSVHeader)
+	for _, item := range items {
+		writer.Write([]string{
+			item.Entry,
+			formatCSVTime(item.Until),
+			formatCSVTime(item.MutedAt),
+			item.Source,
+			item.ActorHuid,
+			item.ActorName,
+			item.Reason,
+		})
+	}
+	writer.Flush()
+	return buffer.Bytes(), writer.Error()
+}
+
+func readMutesCSV(reader io.Reader) ([]MuteItem, error) {
+	records, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return []MuteItem{}, nil
+	}
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.TrimSpace(strings.ToLower(name))] = i
+	}
+	entryColumn, ok := columns["entry"]
+	if !ok {
+		return nil, errors.New("header must contain entry column")
+	}
+	items := []MuteItem{}
+	for line, record := range records[1:] {
+		item := MuteItem{Entry: record[entryColumn]}
+		if column, ok := columns["until"]; ok && strings.TrimSpace(record[column]) != "" {
+			until, err := time.Parse(time.RFC3339, strings.TrimSpace(record[column]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d has invalid until: %w", line+2, err)
+			}
+			item.Until = &until
+		}
+		if column, ok := columns["reason"]; ok {
+			item.Reason = record[column]
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func formatCSVTime(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+	return value.UTC().Format(time.RFC3339)
+}
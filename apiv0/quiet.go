@@ -0,0 +1,62 @@
+package apiv0
+
+import (
+	"log"
+	"sendyxmail/quietmanager"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PriorityHigh messages are delivered during quiet hours.
+const PriorityHigh = "high"
+
+// holdForQuietHours keeps message for digest when quiet hours of chat are on.
+// Messages with high priority and messages that require acknowledgement are never held.
+func holdForQuietHours(ctxData *APIConfig, message Message, origin *messageOrigin, chatId uuid.UUID) (*time.Time, error) {
+	if !mayHoldForQuietHours(ctxData, message) {
+		return nil, nil
+	}
+	payload, err := marshalStoredPayload(message, origin)
+	if err != nil {
+		return nil, err
+	}
+	held, until, err := ctxData.QuietManager.Hold(chatId, payload)
+	if err != nil || !held {
+		return nil, err
+	}
+	return &until, nil
+}
+
+func mayHoldForQuietHours(ctxData *APIConfig, message Message) bool {
+	return ctxData.QuietManager != nil && message.Priority != PriorityHigh && !message.AckRequired
+}
+
+// predictQuietHours returns when quiet hours end if message would be held now.
+func predictQuietHours(ctxData *APIConfig, message Message, chatId uuid.UUID) *time.Time {
+	if !mayHoldForQuietHours(ctxData, message) {
+		return nil
+	}
+	held, until := ctxData.QuietManager.HeldUntil(chatId)
+	if !held {
+		return nil
+	}
+	return &until
+}
+
+// DeliverQuietDigest sends messages held during quiet hours as one message with buttons of all messages.
+// If chat was muted meanwhile, messages with on_muted "queue" are queued until unmute and the rest are dropped.
+// Messages of muted senders are skipped.
+func DeliverQuietDigest(config *APIConfig, chatId uuid.UUID, messages []quietmanager.HeldMessage) error {
+	collected := make([]collectedMessage, 0, len(messages))
+	for _, message := range messages {
+		collected = append(collected, collectedMessage{Payload: message.Payload, ReceivedAt: message.ReceivedAt})
+	}
+	if err := checkAllowedChat(config, chatId); err != nil {
+		queued := queueCollected(config, chatId, collected, err)
+		log.Printf("quiet hours digest to %s is muted, queued %d and dropped %d messages: %s", chatId, queued, len(collected)-queued, err.Error())
+		return nil
+	}
+	parts := buildCollected(config, chatId, collected, time.DateTime)
+	return sendCollected(config, chatId, "🌙 Received during quiet hours / Получено в тихие часы: %d", parts)
+}
@@ -0,0 +1,180 @@
+package apiv0
+
+import (
+	"errors"
+	"net/mail"
+	"regexp"
+	"sendyxmail/usercache"
+	"strings"
+
+	"github.com/go-botx/botx/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Prefixes of user identifiers accepted in `to` besides mail addresses
+const (
+	HuidPrefix  = "huid:"
+	ADPrefix    = "ad:"
+	PhonePrefix = "phone:"
+)
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{5,15}$`)
+
+// parseRecipientAddress normalizes recipient: mail-like address or prefixed user identifier.
+// isUserId is true for huid:, ad: and phone: identifiers.
+func parseRecipientAddress(to string) (addr string, isUserId bool, err error) {
+	to = strings.TrimSpace(to)
+	prefix, value, found := strings.Cut(to, ":")
+	prefix = strings.ToLower(prefix) + ":"
+	if found {
+		switch prefix {
+		case HuidPrefix:
+			huid, err := uuid.Parse(strings.TrimSpace(value))
+			if err != nil {
+				return "", true, errors.New("unable to parse user huid")
+			}
+			return HuidPrefix + huid.String(), true, nil
+		case ADPrefix:
+			login, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(value)), "@")
+			if !ok || login == "" || domain == "" {
+				return "", true, errors.New("AD login must look like ad:<login>@<domain>")
+			}
+			return ADPrefix + login + "@" + domain, true, nil
+		case PhonePrefix:
+			phone := strings.Map(func(r rune) rune {
+				if strings.ContainsRune(" -()", r) {
+					return -1
+				}
+				return r
+			}, value)
+			if !phonePattern.MatchString(phone) {
+				return "", true, errors.New("unable to parse phone number")
+			}
+			return PhonePrefix + phone, true, nil
+		}
+	}
+	mailContact, err := mail.ParseAddress(to)
+	if err != nil {
+		return "", false, errors.New("unable to parse mail address")
+	}
+	return strings.ToLower(mailContact.Address), false, nil
+}
+
+// findUsers looks up users by mail or by prefixed user identifier.
+func findUsers(ctxData *APIConfig, addr string) ([]models.UserInfo, error) {
+	b := ctxData.Bot
+	switch {
+	case strings.HasPrefix(addr, HuidPrefix):
+		huid, err := uuid.Parse(strings.TrimPrefix(addr, HuidPrefix))
+		if err != nil {
+			return nil, err
+		}
+		return b.FindUsersByHuids([]uuid.UUID{huid})
+	case strings.HasPrefix(addr, ADPrefix):
+		login, domain, _ := strings.Cut(strings.TrimPrefix(addr, ADPrefix), "@")
+		return b.FindUsersByADLogin(login, domain)
+	case strings.HasPrefix(addr, PhonePrefix):
+		return b.FindUsersByPhones([]string{strings.TrimPrefix(addr, PhonePrefix)})
+	default:
+		return b.FindUsersByMails([]string{addr})
+	}
+}
+
+// cachedUser returns cached lookup of addr when UserCache is configured.
+func cachedUser(ctxData *APIConfig, addr string) (usercache.Entry, bool) {
+	if ctxData.UserCache == nil {
+		return usercache.Entry{}, false
+	}
+	return ctxData.UserCache.Get(addr)
+}
+
+// chatWithUser finds exactly one cts_user by addr and returns personal chat with the user.
+// Found users and chats and lookups without users are cached when UserCache is configured.
+func chatWithUser(ctxData *APIConfig, addr string) (uuid.UUID, error) {
+	entry, cached := cachedUser(ctxData, addr)
+	if cached && entry.NotFound {
+		return uuid.Nil, newDeliveryError(fiber.StatusNotFound, "no users found")
+	}
+	if cached && entry.ChatId != uuid.Nil {
+		return entry.ChatId, nil
+	}
+	var user models.UserInfo
+	if cached && entry.User != nil {
+		user = *entry.User
+	} else {
+		var err error
+		user, err = lookupSingleUser(ctxData, addr)
+		if err != nil {
+			return uuid.Nil, err
+		}
+	}
+	chatId, err := ctxData.Bot.CreateChatWithUser(user)
+	if err != nil {
+		return uuid.Nil, newDeliveryError(fiber.StatusServiceUnavailable, err.Error())
+	}
+	if ctxData.UserCache != nil {
+		ctxData.UserCache.Put(addr, user, chatId)
+	}
+	return chatId, nil
+}
+
+// knownChatWithUser finds exactly one cts_user by addr like chatWithUser, but never creates personal chat.
+// It returns uuid.Nil if personal chat with the user is not known yet.
+func knownChatWithUser(ctxData *APIConfig, addr string) (uuid.UUID, error) {
+	entry, cached := cachedUser(ctxData, addr)
+	if cached && entry.NotFound {
+		return uuid.Nil, newDeliveryError(fiber.StatusNotFound, "no users found")
+	}
+	if cached {
+		return entry.ChatId, nil
+	}
+	user, err := lookupSingleUser(ctxData, addr)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if ctxData.UserCache != nil {
+		ctxData.UserCache.PutUser(addr, user)
+	}
+	return uuid.Nil, nil
+}
+
+// findSingleUser finds exactly one cts_user by addr using UserCache when it is configured.
+func findSingleUser(ctxData *APIConfig, addr string) (models.UserInfo, error) {
+	entry, cached := cachedUser(ctxData, addr)
+	if cached && entry.NotFound {
+		return models.UserInfo{}, newDeliveryError(fiber.StatusNotFound, "no users found")
+	}
+	if cached && entry.User != nil {
+		return *entry.User, nil
+	}
+	user, err := lookupSingleUser(ctxData, addr)
+	if err != nil {
+		return models.UserInfo{}, err
+	}
+	if ctxData.UserCache != nil {
+		ctxData.UserCache.PutUser(addr, user)
+	}
+	return user, nil
+}
+
+// lookupSingleUser asks server for exactly one cts_user by addr.
+func lookupSingleUser(ctxData *APIConfig, addr string) (models.UserInfo, error) {
+	users, err := findUsers(ctxData, addr)
+	if err != nil {
+		return models.UserInfo{}, newDeliveryError(fiber.StatusInternalServerError, err.Error())
+	}
+	if len(users) <= 0 {
+		if ctxData.UserCache != nil {
+			ctxData.UserCache.PutNotFound(addr)
+		}
+		return models.UserInfo{}, newDeliveryError(fiber.StatusNotFound, "no users found")
+	}
+	if len(users) != 1 {
+		return models.UserInfo{}, newDeliveryError(fiber.StatusExpectationFailed, "found more than one recepients")
+	}
+	if users[0].UserKind != "cts_user" {
+		return models.UserInfo{}, newDeliveryError(fiber.StatusPreconditionRequired, "user is not cts_user")
+	}
+	return users[0], nil
+}
@@ -0,0 +1,113 @@
+package apiv0
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// dryRunMessage does everything deliverMessage does except sending.
+// Acks, escalations and personal chats are not created, only their recipients are checked.
+// Result tells if message would be held for quiet hours, collected for digest or queued until unmuted.
+func dryRunMessage(ctxData *APIConfig, message Message, origin *messageOrigin) (DeliveryResult, error) {
+	if message.Escalation != "" {
+		em := ctxData.EscalationManager
+		if em == nil || ctxData.AckManager == nil {
+			return DeliveryResult{}, newDeliveryError(fiber.StatusNotImplemented, "escalations are not configured")
+		}
+		policy, ok := em.Policy(message.Escalation)
+		if !ok {
+			return DeliveryResult{}, newDeliveryError(fiber.StatusUnprocessableEntity, "unknown escalation policy")
+		}
+		members := make([]string, 0, len(policy.Steps)+1)
+		if message.To != "" {
+			members = append(members, message.To)
+		}
+		for _, step := range policy.Steps {
+			members = append(members, step.To)
+		}
+		message.AckRequired = true
+		return dryRunMembers(ctxData, message, members, origin), nil
+	}
+	if _, members, ok, err := resolveTopic(ctxData, message.To); ok {
+		if err != nil {
+			return DeliveryResult{}, err
+		}
+		return dryRunMembers(ctxData, message, members, origin), nil
+	}
+	if alias, members, ok := resolveAlias(ctxData, message.To); ok {
+		err := checkAllowedAddress(ctxData, alias)
+		if err != nil {
+			return DeliveryResult{}, err
+		}
+		return dryRunMembers(ctxData, message, members, origin), nil
+	}
+	result, err := dryRunSingle(ctxData, message, origin)
+	if err != nil {
+		if details, ok := mayQueue(ctxData, message, err); ok {
+			return DeliveryResult{DryRun: true, Queued: details}, nil
+		}
+		return DeliveryResult{}, err
+	}
+	return result, nil
+}
+
+func dryRunMembers(ctxData *APIConfig, message Message, members []string, origin *messageOrigin) DeliveryResult {
+	result := DeliveryResult{DryRun: true, Members: []MemberResult{}}
+	for _, member := range members {
+		memberMessage := message
+		memberMessage.To = member
+		memberResult := MemberResult{
+			To:     member,
+			Status: fiber.StatusOK,
+			Result: "OK",
+		}
+		predicted, err := dryRunSingle(ctxData, memberMessage, origin)
+		if details, ok := mayQueue(ctxData, memberMessage, err); ok {
+			memberResult.Status = fiber.StatusAccepted
+			memberResult.Result = "queued until unmuted"
+			memberResult.Code = "muted"
+			memberResult.Queued = true
+			memberResult.Mute = details
+		} else if err != nil {
+			memberResult.Status = deliveryErrorStatus(err)
+			memberResult.Result = err.Error()
+			if details, muted := mutedDetails(err); muted {
+				memberResult.Code = "muted"
+				memberResult.Mute = details
+			}
+		} else {
+			if predicted.ChatId != uuid.Nil {
+				memberResult.ChatId = &predicted.ChatId
+			}
+			memberResult.HeldUntil = predicted.HeldUntil
+			memberResult.DigestAt = predicted.DigestAt
+		}
+		result.Members = append(result.Members, memberResult)
+	}
+	return result
+}
+
+// dryRunSingle resolves recipient and builds the message that would be sent to it.
+// ChatId of result is uuid.Nil if personal chat with the user does not exist yet.
+func dryRunSingle(ctxData *APIConfig, message Message, origin *messageOrigin) (DeliveryResult, error) {
+	chatId, ndOpts, err := prepareSingle(ctxData, message, origin, knownChatWithUser)
+	if err != nil {
+		return DeliveryResult{}, err
+	}
+	if message.AckRequired && ctxData.AckManager != nil {
+		ndOpts = append(ndOpts, ackButtonOption(uuid.New()))
+	}
+	_, err = newNDRequest(ctxData, chatId, message.Body, ndOpts)
+	if err != nil {
+		return DeliveryResult{}, newDeliveryError(fiber.StatusUnprocessableEntity, err.Error())
+	}
+	result := DeliveryResult{ChatId: chatId, DryRun: true}
+	if chatId == uuid.Nil {
+		return result, nil
+	}
+	result.HeldUntil = predictQuietHours(ctxData, message, chatId)
+	if result.HeldUntil == nil {
+		result.DigestAt = predictDigest(ctxData, message, chatId)
+	}
+	return result, nil
+}
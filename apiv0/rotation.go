@@ -0,0 +1,112 @@
+package apiv0
+
+import (
+	"errors"
+	"sendyxmail/rotationmanager"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RotationAddressPrefix starts address of on-call rotation: oncall-<name><RotationMailSuffix>.
+const RotationAddressPrefix = "oncall-"
+
+type rotationResponse struct {
+	rotationmanager.Rotation
+	Address string                `json:"address"`
+	Current rotationmanager.Shift `json:"current"`
+	Next    rotationmanager.Shift `json:"next"`
+}
+
+// resolveRotation replaces rotation address with mail of member who is on call now.
+// Other addresses are returned as is.
+func resolveRotation(ctxData *APIConfig, addr string) (string, error) {
+	if ctxData.RotationManager == nil || ctxData.RotationMailSuffix == "" {
+		return addr, nil
+	}
+	name, ok := strings.CutSuffix(addr, ctxData.RotationMailSuffix)
+	if !ok {
+		return addr, nil
+	}
+	name, ok = strings.CutPrefix(name, RotationAddressPrefix)
+	if !ok {
+		return "", newDeliveryError(fiber.StatusNotFound, "rotation address must look like "+RotationAddressPrefix+"<name>"+ctxData.RotationMailSuffix)
+	}
+	mail, err := ctxData.RotationManager.CurrentMail(name)
+	if err != nil {
+		if errors.Is(err, rotationmanager.ErrNotFound) {
+			return "", newDeliveryError(fiber.StatusNotFound, "rotation not found")
+		}
+		return "", err
+	}
+	return mail, nil
+}
+
+func (ctxData *APIConfig) newRotationResponse(rotation rotationmanager.Rotation) rotationResponse {
+	current, next := rotation.OnCall(time.Now())
+	return rotationResponse{
+		Rotation: rotation,
+		Address:  RotationAddressPrefix + rotation.Name + ctxData.RotationMailSuffix,
+		Current:  current,
+		Next:     next,
+	}
+}
+
+func apiListRotationsHandler(c *fiber.Ctx) error {
+	ctxData := extractAppCtxData(c)
+	if ctxData.RotationManager == nil {
+		return sendJsonResponseString(c, fiber.StatusNotImplemented, "rotations are not configured")
+	}
+	response := []rotationResponse{}
+	for _, rotation := range ctxData.RotationManager.List() {
+		response = append(response, ctxData.newRotationResponse(rotation))
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+func apiGetRotationHandler(c *fiber.Ctx) error {
+	ctxData := extractAppCtxData(c)
+	if ctxData.RotationManager == nil {
+		return sendJsonResponseString(c, fiber.StatusNotImplemented, "rotations are not configured")
+	}
+	rotation, ok := ctxData.RotationManager.Get(c.Params("name"))
+	if !ok {
+		return sendJsonResponseString(c, fiber.StatusNotFound, "rotation not found")
+	}
+	return c.Status(fiber.StatusOK).JSON(ctxData.newRotationResponse(rotation))
+}
+
+func apiPutRotationHandler(c *fiber.Ctx) error {
+	ctxData := extractAppCtxData(c)
+	if ctxData.RotationManager == nil {
+		return sendJsonResponseString(c, fiber.StatusNotImplemented, "rotations are not configured")
+	}
+	var rotation rotationmanager.Rotation
+	if err := c.BodyParser(&rotation); err != nil {
+		return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, "unable to parse json")
+	}
+	rotation.Name = c.Params("name")
+	if err := rotation.Validate(); err != nil {
+		return sendJsonResponseString(c, fiber.StatusUnprocessableEntity, err.Error())
+	}
+	if err := ctxData.RotationManager.Set(rotation); err != nil {
+		return sendJsonResponseString(c, fiber.StatusInternalServerError, err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(ctxData.newRotationResponse(rotation))
+}
+
+func apiDeleteRotationHandler(c *fiber.Ctx) error {
+	ctxData := extractAppCtxData(c)
+	if ctxData.RotationManager == nil {
+		return sendJsonResponseString(c, fiber.StatusNotImplemented, "rotations are not configured")
+	}
+	err := ctxData.RotationManager.Delete(c.Params("name"))
+	if errors.Is(err, rotationmanager.ErrNotFound) {
+		return sendJsonResponseString(c, fiber.StatusNotFound, "rotation not found")
+	}
+	if err != nil {
+		return sendJsonResponseString(c, fiber.StatusInternalServerError, err.Error())
+	}
+	return sendJsonResponseString(c, fiber.StatusOK, "OK")
+}
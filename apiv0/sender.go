@@ -0,0 +1,25 @@
+package apiv0
+
+import (
+	"log"
+
+	"github.com/google/uuid"
+)
+
+func senderName(ctxData *APIConfig, tokenId string) string {
+	if ctxData.SenderName == nil {
+		return ""
+	}
+	return ctxData.SenderName(tokenId)
+}
+
+// recordSender remembers that sender posted to chat, so chat admins can find and mute it.
+func recordSender(ctxData *APIConfig, chatId uuid.UUID, sender string) {
+	if ctxData.ChatRegistry == nil || sender == "" {
+		return
+	}
+	err := ctxData.ChatRegistry.RecordSender(chatId, sender)
+	if err != nil {
+		log.Printf("failed to remember sender %s of chat %s: %s", sender, chatId, err.Error())
+	}
+}
@@ -0,0 +1,39 @@
+package apiv0
+
+import (
+	"log"
+
+	"github.com/go-botx/botx/models"
+	"github.com/google/uuid"
+)
+
+// replyOption makes message a reply to message with sync_id or key from reply_to.
+// Message is sent as usual when original message is unknown.
+func replyOption(ctxData *APIConfig, message Message, origin *messageOrigin, chatId uuid.UUID) (models.NDRequestOption, bool) {
+	if message.ReplyTo == "" {
+		return nil, false
+	}
+	syncId, err := uuid.Parse(message.ReplyTo)
+	if err == nil {
+		return models.WithNDReplyTo(syncId), true
+	}
+	if ctxData.ThreadManager == nil {
+		return nil, false
+	}
+	syncId, ok := ctxData.ThreadManager.Find(origin.TokenId, message.ReplyTo, chatId)
+	if !ok {
+		return nil, false
+	}
+	return models.WithNDReplyTo(syncId), true
+}
+
+// recordThread remembers sent message, so later messages can reply to it by key.
+func recordThread(ctxData *APIConfig, message Message, origin *messageOrigin, result DeliveryResult) {
+	if message.Key == "" || ctxData.ThreadManager == nil || result.SyncId == uuid.Nil {
+		return
+	}
+	err := ctxData.ThreadManager.Record(origin.TokenId, message.Key, result.ChatId, result.SyncId)
+	if err != nil {
+		log.Printf("failed to remember message %s with key '%s': %s", result.SyncId, message.Key, err.Error())
+	}
+}
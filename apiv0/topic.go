@@ -0,0 +1,35 @@
+package apiv0
+
+import (
+	"sendyxmail/topicmanager"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TopicPrefix marks topic address: topic:deployments.prod
+const TopicPrefix = "topic:"
+
+// resolveTopic returns addresses of chats subscribed to topic address.
+func resolveTopic(ctxData *APIConfig, to string) (string, []string, bool, error) {
+	prefix, value, found := strings.Cut(strings.TrimSpace(to), ":")
+	if !found || !strings.EqualFold(prefix+":", TopicPrefix) {
+		return "", nil, false, nil
+	}
+	if ctxData.TopicManager == nil {
+		return "", nil, true, newDeliveryError(fiber.StatusNotImplemented, "topics are not configured")
+	}
+	topic, err := topicmanager.NormalizeTopic(value)
+	if err != nil {
+		return "", nil, true, newDeliveryError(fiber.StatusUnprocessableEntity, "topic must look like deployments.prod")
+	}
+	chats := ctxData.TopicManager.Subscribers(topic)
+	if len(chats) == 0 {
+		return "", nil, true, newDeliveryError(fiber.StatusNotFound, "topic has no subscribers")
+	}
+	members := make([]string, 0, len(chats))
+	for _, chatId := range chats {
+		members = append(members, chatId.String()+ctxData.GroupChatMailSuffix)
+	}
+	return TopicPrefix + topic, members, true, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sendyxmail/apiv0"
+	"time"
+
+	"github.com/go-botx/botx/models"
+)
+
+const buttonWebhookSignatureHeader = "X-SendyXMail-Signature"
+
+var buttonWebhookClient = &http.Client{Timeout: 10 * time.Second}
+
+type buttonWebhook struct {
+	Command      string             `json:"command"`
+	Label        string             `json:"label"`
+	Data         any                `json:"data,omitempty"`
+	ChatId       string             `json:"chat_id"`
+	ChatType     string             `json:"chat_type"`
+	SyncId       any                `json:"sync_id"`
+	SourceSyncId any                `json:"source_sync_id"`
+	PressedBy    buttonWebhookActor `json:"pressed_by"`
+	PressedAt    time.Time          `json:"pressed_at"`
+}
+
+type buttonWebhookActor struct {
+	UserHuid any    `json:"user_huid"`
+	Username string `json:"username"`
+	AdLogin  string `json:"ad_login"`
+	AdDomain string `json:"ad_domain"`
+	Locale   string `json:"locale"`
+}
+
+// handleButtonPress forwards pressed data button to callback URL of token which sent the message.
+// Webhook body is signed with HMAC-SHA256 using that token as a key.
+func handleButtonPress(req *models.CommandRequest) {
+	press, err := apiv0.DecodeButtonPress(metadataSecret, req.Command.Data)
+	if err != nil {
+		log.Printf("failed to decode button press in chat %s: %s", req.From.GroupChatId, err.Error())
+		return
+	}
+	token, callbackURL, ok := tm.FindCallback(press.TokenId)
+	if !ok {
+		return
+	}
+	payload, err := json.Marshal(&buttonWebhook{
+		Command:      press.Command,
+		Label:        press.Label,
+		Data:         press.Data,
+		ChatId:       req.From.GroupChatId.String(),
+		ChatType:     string(req.From.ChatType),
+		SyncId:       req.SyncId,
+		SourceSyncId: req.SourceSyncId,
+		PressedBy: buttonWebhookActor{
+			UserHuid: req.From.UserHuid,
+			Username: req.From.Username,
+			AdLogin:  req.From.AdLogin,
+			AdDomain: req.From.AdDomain,
+			Locale:   req.From.Locale,
+		},
+		PressedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("failed to encode button webhook: %s", err.Error())
+		return
+	}
+	err = postSignedWebhook(callbackURL, token, payload)
+	if err != nil {
+		log.Printf("failed to forward button press to %s: %s", callbackURL, err.Error())
+	}
+}
+
+func postSignedWebhook(url string, key string, payload []byte) error {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(payload)
+
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json; charset=utf-8")
+	request.Header.Set("User-Agent", AppName()+"/"+AppVersion())
+	request.Header.Set(buttonWebhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	response, err := buttonWebhookClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("callback responded with status %d", response.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sendyxmail/chataliasmanager"
+	"strings"
+
+	"github.com/go-botx/botx"
+	"github.com/go-botx/botx/models"
+)
+
+// handleChatAlias manages readable addresses of the chat:
+// /alias, /alias set <name>, /alias move <name>, /alias remove <name>
+func handleChatAlias(b *botx.Bot, req *models.CommandRequest, args string) {
+	chatId := req.From.GroupChatId
+	locale := req.From.Locale
+	action, name, _ := strings.Cut(args, " ")
+	name = strings.TrimSpace(name)
+	address := name + chatAliasMailSuffix
+
+	var text string
+	switch {
+	case action == "":
+		text = formatChatAliases(locale, cam.ForChat(chatId))
+		if text == "" {
+			text = getLocalizedMessage(locale, "alias_none")
+		}
+	case name == "":
+		text = getLocalizedMessage(locale, "alias_usage")
+	case action == "set":
+		changed, err := cam.Set(name, chatId, commandSenderHuid(req))
+		text = chatAliasResponse(locale, "alias_set", "alias_exists", changed, err, address)
+	case action == "move":
+		changed, err := cam.Move(name, chatId, commandSenderHuid(req))
+		text = chatAliasResponse(locale, "alias_moved", "alias_exists", changed, err, address)
+	case action == "remove":
+		err := cam.Remove(name, chatId)
+		text = chatAliasResponse(locale, "alias_removed", "", true, err, address)
+	default:
+		text = getLocalizedMessage(locale, "alias_usage")
+	}
+
+	message, err := models.NewNDRequest(chatId, text)
+	if err != nil {
+		return
+	}
+	b.SendMessageAsync(message)
+}
+
+func chatAliasResponse(locale string, changedId string, unchangedId string, changed bool, err error, address string) string {
+	messageId := changedId
+	switch {
+	case errors.Is(err, chataliasmanager.ErrInvalidName):
+		messageId = "alias_invalid"
+	case errors.Is(err, chataliasmanager.ErrTaken):
+		messageId = "alias_taken"
+	case errors.Is(err, chataliasmanager.ErrNotFound):
+		messageId = "alias_not_found"
+	case errors.Is(err, chataliasmanager.ErrNotOwner):
+		messageId = "alias_not_owner"
+	case err != nil:
+		log.Printf("failed to change chat alias %s: %s", address, err.Error())
+		return getLocalizedMessage(locale, "error")
+	case !changed:
+		messageId = unchangedId
+	}
+	text := getLocalizedMessage(locale, messageId)
+	if strings.Contains(text, "%s") {
+		text = fmt.Sprintf(text, address)
+	}
+	return text
+}
+
+// formatChatAliases returns line with readable addresses of the chat or empty string.
+func formatChatAliases(locale string, names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	addresses := []string{}
+	for _, name := range names {
+		addresses = append(addresses, "`"+name+chatAliasMailSuffix+"`")
+	}
+	return fmt.Sprintf(getLocalizedMessage(locale, "show_chat_aliases"), strings.Join(addresses, ", "))
+}
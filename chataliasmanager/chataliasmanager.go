@@ -0,0 +1,162 @@
+package chataliasmanager
+
+import (
+	"errors"
+	"regexp"
+	"sendyxmail/filestore"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChatAlias is readable name of a chat.
+type ChatAlias struct {
+	Name      string    `json:"name"`
+	ChatId    uuid.UUID `json:"chat_id"`
+	OwnerHuid string    `json:"owner_huid,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ChatAliasManager struct {
+	file    string
+	aliases map[string]*ChatAlias
+	mutex   sync.RWMutex
+}
+
+var (
+	ErrInvalidName = errors.New("invalid alias name")
+	ErrTaken       = errors.New("alias is used by another chat")
+	ErrNotFound    = errors.New("alias not found")
+	ErrNotOwner    = errors.New("alias can be moved only by the user who set it")
+
+	namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{1,62}$`)
+)
+
+func New(file string) (*ChatAliasManager, error) {
+	cam := &ChatAliasManager{
+		file:    file,
+		aliases: map[string]*ChatAlias{},
+	}
+	err := filestore.LoadJSON(cam.file, &cam.aliases)
+	if err != nil {
+		return nil, err
+	}
+	return cam, nil
+}
+
+// NormalizeName lowercases alias name and checks that it is valid.
+func NormalizeName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !namePattern.MatchString(name) {
+		return "", ErrInvalidName
+	}
+	return name, nil
+}
+
+// Resolve returns chat of alias.
+func (cam *ChatAliasManager) Resolve(name string) (uuid.UUID, bool) {
+	cam.mutex.RLock()
+	defer cam.mutex.RUnlock()
+	alias, ok := cam.aliases[strings.ToLower(name)]
+	if !ok {
+		return uuid.Nil, false
+	}
+	return alias.ChatId, true
+}
+
+// ForChat returns sorted names of aliases of the chat.
+func (cam *ChatAliasManager) ForChat(chatId uuid.UUID) []string {
+	cam.mutex.RLock()
+	defer cam.mutex.RUnlock()
+	names := []string{}
+	for name, alias := range cam.aliases {
+		if alias.ChatId == chatId {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Set registers alias for the chat. Alias that belongs to another chat is not changed.
+func (cam *ChatAliasManager) Set(name string, chatId uuid.UUID, ownerHuid string) (changed bool, err error) {
+	name, err = NormalizeName(name)
+	if err != nil {
+		return false, err
+	}
+	cam.mutex.Lock()
+	defer cam.mutex.Unlock()
+	if current, ok := cam.aliases[name]; ok {
+		if current.ChatId != chatId {
+			return false, ErrTaken
+		}
+		return false, nil
+	}
+	cam.aliases[name] = &ChatAlias{
+		Name:      name,
+		ChatId:    chatId,
+		OwnerHuid: ownerHuid,
+		UpdatedAt: time.Now().UTC(),
+	}
+	err = cam.save()
+	if err != nil {
+		delete(cam.aliases, name)
+		return false, err
+	}
+	return true, nil
+}
+
+// Move points existing alias to another chat.
+// Only the user who set the alias can move it.
+func (cam *ChatAliasManager) Move(name string, chatId uuid.UUID, userHuid string) (changed bool, err error) {
+	name, err = NormalizeName(name)
+	if err != nil {
+		return false, err
+	}
+	cam.mutex.Lock()
+	defer cam.mutex.Unlock()
+	current, ok := cam.aliases[name]
+	if !ok {
+		return false, ErrNotFound
+	}
+	if current.ChatId == chatId {
+		return false, nil
+	}
+	if current.OwnerHuid == "" || current.OwnerHuid != userHuid {
+		return false, ErrNotOwner
+	}
+	previous := *current
+	current.ChatId = chatId
+	current.UpdatedAt = time.Now().UTC()
+	err = cam.save()
+	if err != nil {
+		*current = previous
+		return false, err
+	}
+	return true, nil
+}
+
+// Remove deletes alias of the chat.
+func (cam *ChatAliasManager) Remove(name string, chatId uuid.UUID) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	cam.mutex.Lock()
+	defer cam.mutex.Unlock()
+	current, ok := cam.aliases[name]
+	if !ok || current.ChatId != chatId {
+		return ErrNotFound
+	}
+	delete(cam.aliases, name)
+	err := cam.save()
+	if err != nil {
+		cam.aliases[name] = current
+		return err
+	}
+	return nil
+}
+
+func (cam *ChatAliasManager) save() error {
+	return filestore.SaveJSON(cam.file, cam.aliases)
+}
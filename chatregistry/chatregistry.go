@@ -0,0 +1,193 @@
+package chatregistry
+
+import (
+	"maps"
+	"sendyxmail/filestore"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Chat is a chat the bot is member of.
+type Chat struct {
+	ChatId       uuid.UUID  `json:"chat_id"`
+	Name         string     `json:"name,omitempty"`
+	ChatType     string     `json:"chat_type"`
+	MembersCount int        `json:"members_count"`
+	JoinedAt     *time.Time `json:"joined_at,omitempty"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	// Senders are names of integrations which posted to the chat and time of their last message
+	Senders map[string]time.Time `json:"senders,omitempty"`
+	// CreatedBy is token id of API client which created the chat
+	CreatedBy string `json:"created_by,omitempty"`
+}
+
+// senderSaveInterval limits how often file is saved when the same sender posts again.
+const senderSaveInterval = time.Hour
+
+type ChatRegistry struct {
+	file  string
+	chats map[uuid.UUID]*Chat
+	mutex sync.RWMutex
+}
+
+func New(file string) (*ChatRegistry, error) {
+	cr := &ChatRegistry{
+		file:  file,
+		chats: map[uuid.UUID]*Chat{},
+	}
+	err := filestore.LoadJSON(cr.file, &cr.chats)
+	if err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// Join registers chat the bot was added to.
+// isNew is false if chat was already known.
+func (cr *ChatRegistry) Join(chatId uuid.UUID, chatType string, name string, membersCount int) (isNew bool, err error) {
+	now := time.Now().UTC()
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+	chat, ok := cr.chats[chatId]
+	if !ok {
+		chat = &Chat{ChatId: chatId}
+		cr.chats[chatId] = chat
+	}
+	if chat.JoinedAt == nil {
+		chat.JoinedAt = &now
+	}
+	chat.ChatType = chatType
+	if name != "" {
+		chat.Name = name
+	}
+	if membersCount > 0 {
+		chat.MembersCount = membersCount
+	}
+	chat.UpdatedAt = now
+	return !ok, cr.save()
+}
+
+// Create registers chat created by API client with token id createdBy.
+func (cr *ChatRegistry) Create(chatId uuid.UUID, name string, createdBy string) error {
+	now := time.Now().UTC()
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+	chat, ok := cr.chats[chatId]
+	if !ok {
+		chat = &Chat{ChatId: chatId, ChatType: "group_chat", JoinedAt: &now}
+		cr.chats[chatId] = chat
+	}
+	if chat.Name == "" {
+		chat.Name = name
+	}
+	chat.CreatedBy = createdBy
+	chat.UpdatedAt = now
+	return cr.save()
+}
+
+// Touch registers chat the bot received command from, if it is not known yet.
+// Join time of such chats is unknown.
+func (cr *ChatRegistry) Touch(chatId uuid.UUID, chatType string) error {
+	cr.mutex.RLock()
+	_, ok := cr.chats[chatId]
+	cr.mutex.RUnlock()
+	if ok {
+		return nil
+	}
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+	if _, ok := cr.chats[chatId]; ok {
+		return nil
+	}
+	cr.chats[chatId] = &Chat{
+		ChatId:    chatId,
+		ChatType:  chatType,
+		UpdatedAt: time.Now().UTC(),
+	}
+	return cr.save()
+}
+
+// RecordSender remembers that sender posted to the chat.
+func (cr *ChatRegistry) RecordSender(chatId uuid.UUID, sender string) error {
+	now := time.Now().UTC()
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+	chat, ok := cr.chats[chatId]
+	if !ok {
+		chat = &Chat{ChatId: chatId, UpdatedAt: now}
+		cr.chats[chatId] = chat
+	}
+	if chat.Senders == nil {
+		chat.Senders = map[string]time.Time{}
+	}
+	lastSeen, known := chat.Senders[sender]
+	chat.Senders[sender] = now
+	if known && now.Sub(lastSeen) < senderSaveInterval {
+		return nil
+	}
+	return cr.save()
+}
+
+// ChangeMembers adjusts members count of known chat by delta.
+func (cr *ChatRegistry) ChangeMembers(chatId uuid.UUID, delta int) error {
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+	chat, ok := cr.chats[chatId]
+	if !ok {
+		return nil
+	}
+	chat.MembersCount = max(chat.MembersCount+delta, 0)
+	chat.UpdatedAt = time.Now().UTC()
+	return cr.save()
+}
+
+// Leave removes chat the bot was deleted from or left.
+func (cr *ChatRegistry) Leave(chatId uuid.UUID) error {
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+	if _, ok := cr.chats[chatId]; !ok {
+		return nil
+	}
+	delete(cr.chats, chatId)
+	return cr.save()
+}
+
+func (cr *ChatRegistry) Get(chatId uuid.UUID) (Chat, bool) {
+	cr.mutex.RLock()
+	defer cr.mutex.RUnlock()
+	chat, ok := cr.chats[chatId]
+	if !ok {
+		return Chat{}, false
+	}
+	return chat.clone(), true
+}
+
+func (c *Chat) clone() Chat {
+	clone := *c
+	clone.Senders = maps.Clone(c.Senders)
+	return clone
+}
+
+// List returns all known chats sorted by name.
+func (cr *ChatRegistry) List() []Chat {
+	cr.mutex.RLock()
+	defer cr.mutex.RUnlock()
+	list := []Chat{}
+	for _, chat := range cr.chats {
+		list = append(list, chat.clone())
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].ChatId.String() < list[j].ChatId.String()
+	})
+	return list
+}
+
+func (cr *ChatRegistry) save() error {
+	return filestore.SaveJSON(cr.file, cr.chats)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"log"
+	"sendyxmail/apiv0"
 	"slices"
 	"strings"
 
@@ -10,52 +11,121 @@ import (
 	"github.com/go-botx/botx/models"
 )
 
+// commandSenderHuid returns HUID of user who sent command or empty string.
+func commandSenderHuid(req *models.CommandRequest) string {
+	if req.From.UserHuid == nil {
+		return ""
+	}
+	return req.From.UserHuid.String()
+}
+
 func NewCommandHandler() botx.CommandCallbackHandler {
 	return func(b *botx.Bot, req *models.CommandRequest) {
 		chatId := req.From.GroupChatId
 		isAdmin := req.From.IsAdmin || req.From.ChatType == models.ChatTypeChat
 
+		if req.Command.CommandType == models.CommandTypeSystem {
+			handleSystemEvent(b, req)
+			return
+		}
+
 		if req.Command.CommandType == models.CommandTypeUser {
+			if err := cr.Touch(chatId, string(req.From.ChatType)); err != nil {
+				log.Printf("failed to register chat %s: %s", chatId, err.Error())
+			}
 			bodyParts := strings.SplitN(strings.TrimSpace(strings.ToLower(req.Command.Body)), " ", 2)
 			if len(bodyParts) < 1 {
 				return
 			}
 			command := bodyParts[0]
+			args := ""
+			if len(bodyParts) > 1 {
+				args = strings.TrimSpace(bodyParts[1])
+			}
 			if slices.Contains([]string{commandMute.Body, commandUnmute.Body}, command) {
-				responseString := ""
 				if !isAdmin {
-					responseString = "not_admin"
+					message, err := models.NewNDRequest(chatId, getLocalizedMessage(req.From.Locale, "not_admin"))
+					if err != nil {
+						return
+					}
+					b.SendMessageAsync(message)
 				} else {
-					changed, err := mm.SetMute(chatId.String(), command == commandMute.Body)
+					handleMute(b, req, command, args)
+				}
+			}
+			if command == commandStatus.Body {
+				handleStatus(b, req)
+			}
+			if command == commandSenders.Body {
+				if !isAdmin {
+					message, err := models.NewNDRequest(chatId, getLocalizedMessage(req.From.Locale, "not_admin"))
 					if err != nil {
-						log.Printf("failed to change mute for %s to %t: %s", chatId, command == commandMute.Body, err.Error())
-						responseString = "error"
-					} else if !changed {
-						if command == commandMute.Body {
-							responseString = "not_changed_muted"
-						} else {
-							responseString = "not_changed_unmuted"
-						}
-
-					} else {
-						if command == commandMute.Body {
-							responseString = "muted"
-						} else {
-							responseString = "unmuted"
-						}
+						return
 					}
+					b.SendMessageAsync(message)
+				} else {
+					handleSenders(b, req)
 				}
-
-				if responseString != "" {
-					message, err := models.NewNDRequest(chatId, getLocalizedMessage(req.From.Locale, responseString))
+			}
+			if slices.Contains([]string{commandSubscribe.Body, commandUnsubscribe.Body}, command) {
+				if !isAdmin {
+					message, err := models.NewNDRequest(chatId, getLocalizedMessage(req.From.Locale, "not_admin"))
 					if err != nil {
 						return
 					}
 					b.SendMessageAsync(message)
+				} else {
+					handleTopics(b, req, command, args)
+				}
+			}
+			if command == commandDigest.Body {
+				if !isAdmin {
+					message, err := models.NewNDRequest(chatId, getLocalizedMessage(req.From.Locale, "not_admin"))
+					if err != nil {
+						return
+					}
+					b.SendMessageAsync(message)
+				} else {
+					handleDigest(b, req, args)
+				}
+			}
+			if command == commandQuiet.Body {
+				if !isAdmin {
+					message, err := models.NewNDRequest(chatId, getLocalizedMessage(req.From.Locale, "not_admin"))
+					if err != nil {
+						return
+					}
+					b.SendMessageAsync(message)
+				} else {
+					handleQuiet(b, req)
+				}
+			}
+			if command == apiv0.ButtonCommand {
+				go handleButtonPress(req)
+			}
+			if command == apiv0.AckCommand {
+				handleAck(b, req)
+			}
+			if command == commandOnCall.Body {
+				handleOnCall(b, req, args)
+			}
+			if command == commandAlias.Body {
+				if !isAdmin {
+					message, err := models.NewNDRequest(chatId, getLocalizedMessage(req.From.Locale, "not_admin"))
+					if err != nil {
+						return
+					}
+					b.SendMessageAsync(message)
+				} else {
+					handleChatAlias(b, req, args)
 				}
 			}
 			if command == commandChatAddr.Body && isAdmin {
-				message, err := models.NewNDRequest(chatId, fmt.Sprintf(getLocalizedMessage(req.From.Locale, "show_chat_addr"), fmt.Sprintf("%s%s", chatId.String(), groupChatMailSuffix)))
+				text := fmt.Sprintf(getLocalizedMessage(req.From.Locale, "show_chat_addr"), fmt.Sprintf("%s%s", chatId.String(), groupChatMailSuffix))
+				if aliases := formatChatAliases(req.From.Locale, cam.ForChat(chatId)); aliases != "" {
+					text += "\n" + aliases
+				}
+				message, err := models.NewNDRequest(chatId, text)
 				if err != nil {
 					return
 				}
@@ -12,6 +12,38 @@ var (
 		Body:        "/unmute",
 		Description: "Unmute notifications in this chat 🔔",
 	}
+	commandStatus = models.StatusResponseCommand{
+		Body:        "/status",
+		Description: "Show who muted this chat and why 🔍",
+	}
+	commandSenders = models.StatusResponseCommand{
+		Body:        "/senders",
+		Description: "Show integrations that posted to this chat 📨",
+	}
+	commandSubscribe = models.StatusResponseCommand{
+		Body:        "/subscribe",
+		Description: "Subscribe this chat to topics 📬",
+	}
+	commandUnsubscribe = models.StatusResponseCommand{
+		Body:        "/unsubscribe",
+		Description: "Unsubscribe this chat from topics 📭",
+	}
+	commandDigest = models.StatusResponseCommand{
+		Body:        "/digest",
+		Description: "Combine notifications into one message per interval 📋",
+	}
+	commandQuiet = models.StatusResponseCommand{
+		Body:        "/quiet",
+		Description: "Hold notifications during quiet hours 🌙",
+	}
+	commandOnCall = models.StatusResponseCommand{
+		Body:        "/oncall",
+		Description: "Show who is on call now and next 📟",
+	}
+	commandAlias = models.StatusResponseCommand{
+		Body:        "/alias",
+		Description: "Manage readable address of this chat 🏷️",
+	}
 	commandChatAddr = models.StatusResponseCommand{
 		Body:        "/_address",
 		Description: "Get address for current chat",
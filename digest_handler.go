@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sendyxmail/digestmanager"
+	"strings"
+	"time"
+
+	"github.com/go-botx/botx"
+	"github.com/go-botx/botx/models"
+)
+
+const digestOff = "off"
+
+// handleDigest shows, sets or turns off digest mode of chat.
+func handleDigest(b *botx.Bot, req *models.CommandRequest, args string) {
+	chatId := req.From.GroupChatId
+	locale := req.From.Locale
+	text := ""
+	switch args {
+	case "":
+		interval, ok := dgm.Interval(chatId)
+		if !ok {
+			text = getLocalizedMessage(locale, "digest_none")
+		} else {
+			text = fmt.Sprintf(getLocalizedMessage(locale, "digest_show"), formatInterval(interval))
+		}
+	case digestOff:
+		removed, err := dgm.Remove(chatId)
+		if err != nil {
+			log.Printf("failed to turn off digest of %s: %s", chatId, err.Error())
+			text = getLocalizedMessage(locale, "error")
+		} else if !removed {
+			text = getLocalizedMessage(locale, "digest_none")
+		} else {
+			text = getLocalizedMessage(locale, "digest_off")
+		}
+	default:
+		interval, err := time.ParseDuration(args)
+		if err != nil {
+			text = getLocalizedMessage(locale, "digest_usage")
+			break
+		}
+		err = dgm.SetInterval(chatId, interval)
+		if errors.Is(err, digestmanager.ErrInvalidInterval) {
+			text = getLocalizedMessage(locale, "digest_usage")
+		} else if err != nil {
+			log.Printf("failed to set digest of %s: %s", chatId, err.Error())
+			text = getLocalizedMessage(locale, "error")
+		} else {
+			text = fmt.Sprintf(getLocalizedMessage(locale, "digest_set"), formatInterval(interval))
+		}
+	}
+	message, err := models.NewNDRequest(chatId, text)
+	if err != nil {
+		return
+	}
+	b.SendMessageAsync(message)
+}
+
+// formatInterval prints 15m instead of 15m0s.
+func formatInterval(interval time.Duration) string {
+	text := interval.String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
@@ -0,0 +1,218 @@
+package digestmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sendyxmail/filestore"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Limits of digest interval
+const (
+	MinInterval = time.Minute
+	MaxInterval = 24 * time.Hour
+)
+
+// MaxBuffered is the maximum number of messages collected for one digest.
+// The oldest messages are dropped when it is exceeded.
+const MaxBuffered = 500
+
+var ErrInvalidInterval = errors.New("digest interval must be from 1m to 24h")
+
+// Message is a message waiting for digest.
+// Payload is opaque for DigestManager.
+type Message struct {
+	Payload    json.RawMessage `json:"payload"`
+	ReceivedAt time.Time       `json:"received_at"`
+}
+
+// Buffer is messages collected for the next digest of chat.
+type Buffer struct {
+	Messages  []Message `json:"messages"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// FlushFunc delivers digest of messages to chat.
+// Messages are kept and retried later if it returns error.
+type FlushFunc func(chatId uuid.UUID, messages []Message) error
+
+type state struct {
+	Intervals map[uuid.UUID]time.Duration `json:"intervals"`
+	Buffers   map[uuid.UUID]*Buffer       `json:"buffers"`
+}
+
+type DigestManager struct {
+	file  string
+	state state
+	flush FlushFunc
+	mutex sync.RWMutex
+}
+
+// Run loads digest settings and buffered messages from file and starts sending digests.
+func Run(file string, flush FlushFunc) (*DigestManager, error) {
+	dm := &DigestManager{
+		file: file,
+		state: state{
+			Intervals: map[uuid.UUID]time.Duration{},
+			Buffers:   map[uuid.UUID]*Buffer{},
+		},
+		flush: flush,
+	}
+	err := filestore.LoadJSON(dm.file, &dm.state)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			time.Sleep(10 * time.Second)
+			dm.flushDue()
+		}
+	}()
+	return dm, nil
+}
+
+// Interval returns digest interval of chat.
+func (dm *DigestManager) Interval(chatId uuid.UUID) (time.Duration, bool) {
+	dm.mutex.RLock()
+	defer dm.mutex.RUnlock()
+	interval, ok := dm.state.Intervals[chatId]
+	return interval, ok
+}
+
+// SetInterval turns digest mode of chat on.
+func (dm *DigestManager) SetInterval(chatId uuid.UUID, interval time.Duration) error {
+	if interval < MinInterval || interval > MaxInterval {
+		return ErrInvalidInterval
+	}
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+	previous, existed := dm.state.Intervals[chatId]
+	dm.state.Intervals[chatId] = interval
+	err := dm.save()
+	if err != nil {
+		if existed {
+			dm.state.Intervals[chatId] = previous
+		} else {
+			delete(dm.state.Intervals, chatId)
+		}
+		return err
+	}
+	return nil
+}
+
+// Remove turns digest mode of chat off. Collected messages are sent soon.
+func (dm *DigestManager) Remove(chatId uuid.UUID) (bool, error) {
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+	previous, existed := dm.state.Intervals[chatId]
+	if !existed {
+		return false, nil
+	}
+	delete(dm.state.Intervals, chatId)
+	err := dm.save()
+	if err != nil {
+		dm.state.Intervals[chatId] = previous
+		return false, err
+	}
+	return true, nil
+}
+
+// Add collects message for digest if digest mode of chat is on.
+// It returns false if message must be delivered now, otherwise time when digest is sent.
+func (dm *DigestManager) Add(chatId uuid.UUID, payload json.RawMessage) (bool, time.Time, error) {
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+	interval, ok := dm.state.Intervals[chatId]
+	if !ok {
+		return false, time.Time{}, nil
+	}
+	now := time.Now().UTC()
+	buffer, existed := dm.state.Buffers[chatId]
+	if !existed {
+		buffer = &Buffer{Messages: []Message{}, StartedAt: now}
+		dm.state.Buffers[chatId] = buffer
+	}
+	previous := buffer.Messages
+	buffer.Messages = limitBuffered(append(previous[:len(previous):len(previous)], Message{Payload: payload, ReceivedAt: now}))
+	err := dm.save()
+	if err != nil {
+		if existed {
+			buffer.Messages = previous
+		} else {
+			delete(dm.state.Buffers, chatId)
+		}
+		return false, time.Time{}, err
+	}
+	return true, buffer.StartedAt.Add(interval), nil
+}
+
+// NextDigest reports if message received now would be collected and when digest is sent.
+func (dm *DigestManager) NextDigest(chatId uuid.UUID) (bool, time.Time) {
+	dm.mutex.RLock()
+	defer dm.mutex.RUnlock()
+	interval, ok := dm.state.Intervals[chatId]
+	if !ok {
+		return false, time.Time{}
+	}
+	if buffer, ok := dm.state.Buffers[chatId]; ok {
+		return true, buffer.StartedAt.Add(interval)
+	}
+	return true, time.Now().UTC().Add(interval)
+}
+
+// flushDue sends digests which interval is over and digests of chats with digest mode turned off.
+func (dm *DigestManager) flushDue() {
+	now := time.Now()
+	due := map[uuid.UUID]*Buffer{}
+	dm.mutex.Lock()
+	for chatId, buffer := range dm.state.Buffers {
+		interval, ok := dm.state.Intervals[chatId]
+		if ok && now.Before(buffer.StartedAt.Add(interval)) {
+			continue
+		}
+		due[chatId] = buffer
+		delete(dm.state.Buffers, chatId)
+	}
+	dm.mutex.Unlock()
+	if len(due) == 0 {
+		return
+	}
+
+	failed := map[uuid.UUID]*Buffer{}
+	for chatId, buffer := range due {
+		err := dm.flush(chatId, buffer.Messages)
+		if err != nil {
+			log.Printf("failed to deliver digest to %s: %s", chatId, err.Error())
+			failed[chatId] = buffer
+		}
+	}
+
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+	for chatId, buffer := range failed {
+		if newer, ok := dm.state.Buffers[chatId]; ok {
+			buffer.Messages = limitBuffered(append(buffer.Messages, newer.Messages...))
+		}
+		dm.state.Buffers[chatId] = buffer
+	}
+	err := dm.save()
+	if err != nil {
+		log.Printf("failed to save digests: %s", err.Error())
+	}
+}
+
+// limitBuffered drops the oldest messages above MaxBuffered.
+func limitBuffered(messages []Message) []Message {
+	if len(messages) > MaxBuffered {
+		return messages[len(messages)-MaxBuffered:]
+	}
+	return messages
+}
+
+func (dm *DigestManager) save() error {
+	return filestore.SaveJSON(dm.file, dm.state)
+}
@@ -0,0 +1,255 @@
+package escalationmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sendyxmail/filestore"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Step outcomes
+const (
+	OutcomeSent     = "sent"
+	OutcomeNotFound = "not_found"
+	OutcomeMuted    = "muted"
+	OutcomeFailed   = "failed"
+)
+
+// Escalation finish reasons
+const (
+	FinishedAcknowledged = "acknowledged"
+	FinishedExhausted    = "exhausted"
+)
+
+// Escalation is one run of a message through policy steps.
+type Escalation struct {
+	Id         uuid.UUID       `json:"id"`
+	Policy     Policy          `json:"policy"`
+	AckId      uuid.UUID       `json:"ack_id"`
+	Payload    json.RawMessage `json:"payload"`
+	CreatedAt  time.Time       `json:"created_at"`
+	NextStep   int             `json:"next_step"`
+	NextStepAt time.Time       `json:"next_step_at"`
+	Steps      []StepRecord    `json:"steps"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+	Finished   string          `json:"finished,omitempty"`
+	// busy is set while steps of the escalation are executed
+	busy bool
+}
+
+// StepRecord is outcome of one executed step.
+type StepRecord struct {
+	To        string    `json:"to"`
+	StartedAt time.Time `json:"started_at"`
+	Outcome   string    `json:"outcome"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// StepFunc delivers run payload to step recipient and returns outcome.
+type StepFunc func(run Escalation, to string) (outcome string, err error)
+
+// AckedFunc reports if message of the run was acknowledged.
+type AckedFunc func(ackId uuid.UUID) bool
+
+type EscalationManager struct {
+	file      string
+	retention time.Duration
+	policies  *policySet
+	runs      map[uuid.UUID]*Escalation
+	deliver   StepFunc
+	isAcked   AckedFunc
+	mutex     sync.Mutex
+}
+
+var ErrUnknownPolicy = errors.New("unknown escalation policy")
+
+// Run loads policies and stored runs and starts executing steps.
+// Finished runs are forgotten after retention.
+func Run(policyFile string, runsFile string, retention time.Duration, deliver StepFunc, isAcked AckedFunc) (*EscalationManager, error) {
+	policies, err := loadPolicySet(policyFile, time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	em := &EscalationManager{
+		file:      runsFile,
+		retention: retention,
+		policies:  policies,
+		runs:      map[uuid.UUID]*Escalation{},
+		deliver:   deliver,
+		isAcked:   isAcked,
+	}
+	err = filestore.LoadJSON(em.file, &em.runs)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			time.Sleep(15 * time.Second)
+			em.advance()
+		}
+	}()
+	return em, nil
+}
+
+// HasPolicy reports if policy with given name is configured.
+func (em *EscalationManager) HasPolicy(name string) bool {
+	_, ok := em.policies.get(name)
+	return ok
+}
+
+// Policy returns configured policy with given name.
+func (em *EscalationManager) Policy(name string) (Policy, bool) {
+	return em.policies.get(name)
+}
+
+// Start creates run and executes its first step immediately.
+// If primary is not empty, it is the first step and policy steps follow it.
+func (em *EscalationManager) Start(policyName string, primary string, ackId uuid.UUID, payload json.RawMessage) (Escalation, error) {
+	policy, ok := em.policies.get(policyName)
+	if !ok {
+		return Escalation{}, ErrUnknownPolicy
+	}
+	if primary != "" {
+		policy.Steps = append([]PolicyStep{{To: primary}}, policy.Steps...)
+	}
+	now := time.Now().UTC()
+	run := &Escalation{
+		Id:         uuid.New(),
+		Policy:     policy,
+		AckId:      ackId,
+		Payload:    payload,
+		CreatedAt:  now,
+		NextStepAt: now,
+		Steps:      []StepRecord{},
+	}
+	em.mutex.Lock()
+	em.runs[run.Id] = run
+	em.mutex.Unlock()
+
+	em.advanceRun(run.Id)
+
+	em.mutex.Lock()
+	defer em.mutex.Unlock()
+	return run.clone(), em.save()
+}
+
+func (em *EscalationManager) Get(id uuid.UUID) (Escalation, bool) {
+	em.mutex.Lock()
+	defer em.mutex.Unlock()
+	run, ok := em.runs[id]
+	if !ok {
+		return Escalation{}, false
+	}
+	return run.clone(), true
+}
+
+func (em *EscalationManager) advance() {
+	now := time.Now().UTC()
+	due := []uuid.UUID{}
+
+	em.mutex.Lock()
+	for id, run := range em.runs {
+		if run.FinishedAt != nil {
+			if now.Sub(*run.FinishedAt) > em.retention {
+				delete(em.runs, id)
+			}
+			continue
+		}
+		due = append(due, id)
+	}
+	em.mutex.Unlock()
+
+	for _, id := range due {
+		em.advanceRun(id)
+	}
+
+	em.mutex.Lock()
+	err := em.save()
+	em.mutex.Unlock()
+	if err != nil {
+		log.Printf("failed to save escalations: %s", err.Error())
+	}
+}
+
+// advanceRun finishes acknowledged run or executes steps which time has come.
+// Step that failed to deliver is immediately followed by the next one.
+func (em *EscalationManager) advanceRun(id uuid.UUID) {
+	em.mutex.Lock()
+	current, ok := em.runs[id]
+	if !ok || current.busy {
+		em.mutex.Unlock()
+		return
+	}
+	current.busy = true
+	em.mutex.Unlock()
+	defer func() {
+		em.mutex.Lock()
+		current.busy = false
+		em.mutex.Unlock()
+	}()
+
+	for {
+		em.mutex.Lock()
+		run, ok := em.runs[id]
+		if !ok || run.FinishedAt != nil {
+			em.mutex.Unlock()
+			return
+		}
+		now := time.Now().UTC()
+		if em.isAcked != nil && em.isAcked(run.AckId) {
+			run.finish(now, FinishedAcknowledged)
+			em.mutex.Unlock()
+			return
+		}
+		if now.Before(run.NextStepAt) {
+			em.mutex.Unlock()
+			return
+		}
+		if run.NextStep >= len(run.Policy.Steps) {
+			run.finish(now, FinishedExhausted)
+			em.mutex.Unlock()
+			return
+		}
+		step := run.Policy.Steps[run.NextStep]
+		snapshot := run.clone()
+		em.mutex.Unlock()
+
+		outcome, err := em.deliver(snapshot, step.To)
+		record := StepRecord{
+			To:        step.To,
+			StartedAt: now,
+			Outcome:   outcome,
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+
+		em.mutex.Lock()
+		run.Steps = append(run.Steps, record)
+		run.NextStep++
+		if outcome == OutcomeSent {
+			run.NextStepAt = now.Add(step.waitDuration())
+		}
+		em.mutex.Unlock()
+	}
+}
+
+func (r *Escalation) finish(now time.Time, reason string) {
+	r.FinishedAt = &now
+	r.Finished = reason
+}
+
+func (em *EscalationManager) save() error {
+	return filestore.SaveJSON(em.file, em.runs)
+}
+
+func (r *Escalation) clone() Escalation {
+	clone := *r
+	clone.Steps = slices.Clone(r.Steps)
+	return clone
+}
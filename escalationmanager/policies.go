@@ -0,0 +1,113 @@
+package escalationmanager
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Policy is named chain of recipients.
+type Policy struct {
+	Name  string       `yaml:"name"`
+	Steps []PolicyStep `yaml:"steps"`
+}
+
+// PolicyStep is one recipient in the chain.
+// Wait is how many minutes to wait for acknowledgement before the next step.
+type PolicyStep struct {
+	To   string `yaml:"to"`
+	Wait int    `yaml:"wait"`
+}
+
+const defaultStepWait = 10 * time.Minute
+
+func (s PolicyStep) waitDuration() time.Duration {
+	if s.Wait <= 0 {
+		return defaultStepWait
+	}
+	return time.Duration(s.Wait) * time.Minute
+}
+
+type policySet struct {
+	file     string
+	modTime  time.Time
+	policies map[string]Policy
+	mutex    sync.RWMutex
+}
+
+func loadPolicySet(file string, refreshInterval time.Duration) (*policySet, error) {
+	ps := &policySet{
+		file:     file,
+		policies: map[string]Policy{},
+	}
+	err := ps.reloadPolicies()
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			time.Sleep(refreshInterval)
+			err := ps.reloadPolicies()
+			if err != nil {
+				log.Printf("failed reloading escalation policies: %s\n", err.Error())
+			}
+		}
+	}()
+	return ps, nil
+}
+
+func (ps *policySet) reloadPolicies() error {
+	info, err := os.Stat(ps.file)
+	if err != nil {
+		return err
+	}
+	ps.mutex.RLock()
+	unchanged := info.ModTime().Equal(ps.modTime)
+	ps.mutex.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	data, err := os.ReadFile(ps.file)
+	if err != nil {
+		return err
+	}
+	var newPolicies []Policy
+	err = yaml.Unmarshal(data, &newPolicies)
+	if err != nil {
+		return err
+	}
+	policies := map[string]Policy{}
+	for idx, policy := range newPolicies {
+		if policy.Name == "" {
+			return fmt.Errorf("escalation policy number %d in file %s has no name", idx+1, ps.file)
+		}
+		if len(policy.Steps) == 0 {
+			return fmt.Errorf("escalation policy '%s' has no steps", policy.Name)
+		}
+		for stepIdx, step := range policy.Steps {
+			if step.To == "" {
+				return fmt.Errorf("step %d of escalation policy '%s' has empty 'to'", stepIdx+1, policy.Name)
+			}
+		}
+		policies[policy.Name] = policy
+	}
+
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	ps.policies = policies
+	ps.modTime = info.ModTime()
+	log.Printf("updated %d escalation policies from %s\n", len(policies), ps.file)
+	return nil
+}
+
+func (ps *policySet) get(name string) (Policy, bool) {
+	ps.mutex.RLock()
+	defer ps.mutex.RUnlock()
+	policy, ok := ps.policies[name]
+	return policy, ok
+}
@@ -0,0 +1,89 @@
+package filestore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// SaveJSON stores value as JSON the same way MuteManager stores mutes:
+// current file is copied to *.bak, new content is written to temporary file
+// in the same folder and then copied over the original file.
+func SaveJSON(file string, value any) error {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = createCopy(file, file+".bak")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	tempFile, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer tempFile.Close()
+	defer os.Remove(tempFile.Name())
+
+	_, err = tempFile.Write(data)
+	if err != nil {
+		return err
+	}
+	err = tempFile.Sync()
+	if err != nil {
+		return err
+	}
+	err = tempFile.Close()
+	if err != nil {
+		return err
+	}
+	return createCopy(tempFile.Name(), file)
+}
+
+// LoadJSON reads value stored by SaveJSON.
+// Missing file is not an error, value is left untouched.
+func LoadJSON(file string, value any) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	err = json.Unmarshal(data, value)
+	if err != nil {
+		return fmt.Errorf("unable to parse %s: %w", file, err)
+	}
+	return nil
+}
+
+func createCopy(src, dst string) error {
+	sourceFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+
+	destFile, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer destFile.Close()
+
+	_, err = io.Copy(destFile, sourceFile)
+	if err != nil {
+		return err
+	}
+	return destFile.Sync()
+}
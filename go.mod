@@ -6,7 +6,10 @@ require (
 	github.com/go-botx/botx v0.0.5
 	github.com/goccy/go-yaml v1.17.1
 	github.com/gofiber/fiber/v2 v2.52.6
+	github.com/gofrs/flock v0.12.1
 	github.com/google/uuid v1.6.0
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.33
 )
 
 require (
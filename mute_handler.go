@@ -0,0 +1,307 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"sendyxmail/mutemanager"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-botx/botx"
+	"github.com/go-botx/botx/models"
+	"github.com/google/uuid"
+)
+
+const (
+	// muteVacation mutes chat until it is unmuted explicitly.
+	muteVacation = "vacation"
+	// muteSender prefixes arguments of /mute and /unmute for one sender.
+	muteSender = "sender"
+	// muteReason separates optional reason from other arguments of /mute and /unmute.
+	muteReason = "reason:"
+	// muteHistoryShown is number of latest changes shown by /status.
+	muteHistoryShown = 5
+)
+
+// handleMute mutes or unmutes chat of the command or one sender in it.
+// /mute accepts duration (2h, 3d, 2w), "until <date>" or "vacation", optionally after "sender <name>".
+// Both commands accept "reason: <text>" at the end, which is kept in mute history.
+func handleMute(b *botx.Bot, req *models.CommandRequest, command string, args string) {
+	chatId := req.From.GroupChatId
+	entry := chatId.String()
+	args, _, _ = strings.Cut(args, muteReason)
+	args = strings.TrimSpace(args)
+	origin := mutemanager.Origin{
+		Source:    mutemanager.SourceChat,
+		ActorHuid: commandSenderHuid(req),
+		ActorName: req.From.Username,
+		Reason:    cutMuteReason(req.Command.Body),
+	}
+	sender := ""
+	var until time.Time
+	var err error
+	changed := false
+	responseString := ""
+	first, rest, _ := strings.Cut(args, " ")
+	isSenderMute := first == muteSender
+	if isSenderMute {
+		sender, args, _ = strings.Cut(strings.TrimSpace(rest), " ")
+		entry = mutemanager.SenderEntry(entry, sender)
+		if sender == "" {
+			responseString = "usage"
+		} else if !isKnownSender(chatId, sender) {
+			responseString = "unknown"
+		}
+	}
+	if responseString == "" {
+		if command == commandMute.Body {
+			until, err = parseMuteUntil(args, time.Now())
+			if err == nil {
+				changed, err = mm.SetMuteUntil(entry, until, origin)
+			} else {
+				responseString = "mute_usage"
+			}
+		} else {
+			changed, err = mm.SetMute(entry, false, origin)
+		}
+	}
+	if responseString == "" {
+		if err != nil {
+			log.Printf("failed to change mute for %s to %t: %s", chatId, command == commandMute.Body, err.Error())
+			responseString = "error"
+		} else if !changed {
+			if command == commandMute.Body {
+				responseString = "not_changed_muted"
+			} else {
+				responseString = "not_changed_unmuted"
+			}
+		} else if command == commandMute.Body && !until.IsZero() {
+			responseString = "muted_until"
+		} else if command == commandMute.Body {
+			responseString = "muted"
+		} else {
+			responseString = "unmuted"
+		}
+	}
+
+	if isSenderMute && responseString != "error" {
+		responseString = "sender_" + responseString
+	}
+	text := getLocalizedMessage(req.From.Locale, responseString)
+	switch {
+	case responseString == "muted_until":
+		text = fmt.Sprintf(text, formatMuteTime(until))
+	case responseString == "sender_muted_until":
+		text = fmt.Sprintf(text, sender, formatMuteTime(until))
+	case strings.HasPrefix(responseString, "sender_") && !strings.HasSuffix(responseString, "usage"):
+		text = fmt.Sprintf(text, sender)
+	}
+	message, err := models.NewNDRequest(chatId, text)
+	if err != nil {
+		return
+	}
+	b.SendMessageAsync(message)
+}
+
+// cutMuteReason returns reason given after "reason:" in command body with original case.
+func cutMuteReason(body string) string {
+	for i := 0; i+len(muteReason) <= len(body); i++ {
+		if strings.EqualFold(body[i:i+len(muteReason)], muteReason) {
+			return strings.TrimSpace(body[i+len(muteReason):])
+		}
+	}
+	return ""
+}
+
+// parseMuteUntil returns expiry of mute described by /mute arguments.
+// Zero time means mute until explicit /unmute.
+func parseMuteUntil(args string, now time.Time) (time.Time, error) {
+	args = strings.TrimSpace(args)
+	if args == "" || args == muteVacation {
+		return time.Time{}, nil
+	}
+	if date, ok := strings.CutPrefix(args, "until "); ok {
+		date = strings.TrimSpace(date)
+		for _, layout := range []string{"2006-01-02 15:04", time.DateOnly} {
+			until, err := time.ParseInLocation(layout, date, time.Local)
+			if err != nil {
+				continue
+			}
+			if !until.After(now) {
+				return time.Time{}, errors.New("mute expiry is in the past")
+			}
+			return until, nil
+		}
+		return time.Time{}, fmt.Errorf("unable to parse date '%s'", date)
+	}
+	duration, err := parseMuteDuration(args)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return now.Add(duration), nil
+}
+
+// parseMuteDuration parses Go duration with additional d (day) and w (week) units.
+func parseMuteDuration(value string) (time.Duration, error) {
+	var duration time.Duration
+	var err error
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		duration, err = parseDays(days, 24*time.Hour)
+	} else if weeks, ok := strings.CutSuffix(value, "w"); ok {
+		duration, err = parseDays(weeks, 7*24*time.Hour)
+	} else {
+		duration, err = time.ParseDuration(value)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if duration <= 0 {
+		return 0, errors.New("mute duration must be positive")
+	}
+	return duration, nil
+}
+
+func parseDays(value string, unit time.Duration) (time.Duration, error) {
+	count, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(count) * unit, nil
+}
+
+// isKnownSender reports if sender has posted to chat, is a configured token name or is already muted in chat.
+// Muted senders are known even if their token was renamed or removed, so they can be unmuted.
+func isKnownSender(chatId uuid.UUID, sender string) bool {
+	if chat, ok := cr.Get(chatId); ok {
+		if _, ok := chat.Senders[sender]; ok {
+			return true
+		}
+	}
+	if _, ok := mm.MutedSenders(chatId.String())[sender]; ok {
+		return true
+	}
+	return sender == syslogSender || tm.HasSender(sender)
+}
+
+// handleSenders lists integrations which posted to chat and their mutes.
+func handleSenders(b *botx.Bot, req *models.CommandRequest) {
+	chatId := req.From.GroupChatId
+	chat, _ := cr.Get(chatId)
+	muted := mm.MutedSenders(chatId.String())
+	senders := slices.Collect(maps.Keys(chat.Senders))
+	for sender := range muted {
+		if !slices.Contains(senders, sender) {
+			senders = append(senders, sender)
+		}
+	}
+	slices.Sort(senders)
+
+	text := getLocalizedMessage(req.From.Locale, "senders_none")
+	if len(senders) > 0 {
+		lines := []string{}
+		for _, sender := range senders {
+			line := fmt.Sprintf("• `%s`", sender)
+			if lastSeen, ok := chat.Senders[sender]; ok {
+				line += " - " + fmt.Sprintf(getLocalizedMessage(req.From.Locale, "sender_last_seen"), formatMuteTime(lastSeen))
+			}
+			if until, ok := muted[sender]; ok {
+				if until.IsZero() {
+					line += " 🔕"
+				} else {
+					line += " 🔕 " + fmt.Sprintf(getLocalizedMessage(req.From.Locale, "sender_muted_till"), formatMuteTime(until))
+				}
+			}
+			lines = append(lines, line)
+		}
+		text = fmt.Sprintf(getLocalizedMessage(req.From.Locale, "senders_list"), strings.Join(lines, "\n"))
+	}
+	message, err := models.NewNDRequest(chatId, text)
+	if err != nil {
+		return
+	}
+	b.SendMessageAsync(message)
+}
+
+// handleStatus shows if chat and its senders are muted, who muted them and latest changes.
+func handleStatus(b *botx.Bot, req *models.CommandRequest) {
+	chatId := req.From.GroupChatId
+	locale := req.From.Locale
+	entry := chatId.String()
+
+	lines := []string{}
+	until, muted := mm.MutedUntil(entry)
+	switch {
+	case !muted:
+		lines = append(lines, getLocalizedMessage(locale, "status_unmuted"))
+	case until.IsZero():
+		lines = append(lines, getLocalizedMessage(locale, "status_muted"))
+	default:
+		lines = append(lines, fmt.Sprintf(getLocalizedMessage(locale, "status_muted_until"), formatMuteTime(until)))
+	}
+	// Event of mute which has just expired is not shown until its unmute event is recorded
+	if event, ok := mm.LastEvent(entry); ok && event.Muted == muted {
+		lines = append(lines, fmt.Sprintf(getLocalizedMessage(locale, "status_changed_by"), formatMuteOrigin(locale, event)))
+	}
+
+	senders := mm.MutedSenders(entry)
+	if len(senders) > 0 {
+		lines = append(lines, "", getLocalizedMessage(locale, "status_senders"))
+		for _, sender := range slices.Sorted(maps.Keys(senders)) {
+			line := fmt.Sprintf("• `%s`", sender)
+			if until := senders[sender]; !until.IsZero() {
+				line += " " + fmt.Sprintf(getLocalizedMessage(locale, "sender_muted_till"), formatMuteTime(until))
+			}
+			if event, ok := mm.LastEvent(mutemanager.SenderEntry(entry, sender)); ok && event.Muted {
+				line += " - " + formatMuteOrigin(locale, event)
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	history, err := mm.History(entry, muteHistoryShown)
+	if err != nil {
+		log.Printf("failed to get mute history of %s: %s", chatId, err.Error())
+	}
+	if len(history) > 0 {
+		lines = append(lines, "", getLocalizedMessage(locale, "status_history"))
+		for _, event := range history {
+			action := getLocalizedMessage(locale, "mute_event_unmuted")
+			if event.Muted && event.Until != nil {
+				action = fmt.Sprintf(getLocalizedMessage(locale, "mute_event_muted_until"), formatMuteTime(*event.Until))
+			} else if event.Muted {
+				action = getLocalizedMessage(locale, "mute_event_muted")
+			}
+			lines = append(lines, fmt.Sprintf("• %s: %s", action, formatMuteOrigin(locale, event)))
+		}
+	}
+
+	message, err := models.NewNDRequest(chatId, strings.Join(lines, "\n"))
+	if err != nil {
+		return
+	}
+	b.SendMessageAsync(message)
+}
+
+// formatMuteOrigin describes who changed mute, when and why.
+func formatMuteOrigin(locale string, event mutemanager.Event) string {
+	actor := event.ActorName
+	if actor == "" {
+		actor = event.ActorHuid
+	}
+	if actor == "" {
+		actor = getLocalizedMessage(locale, "mute_source/"+event.Source)
+	}
+	text := fmt.Sprintf(getLocalizedMessage(locale, "mute_origin"), actor, formatMuteTime(event.Time))
+	if event.Reason != "" {
+		text += ", " + fmt.Sprintf(getLocalizedMessage(locale, "mute_reason"), event.Reason)
+	}
+	return text
+}
+
+func formatMuteTime(until time.Time) string {
+	return until.Local().Format("2006-01-02 15:04 MST")
+}
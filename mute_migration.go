@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sendyxmail/mutemanager"
+)
+
+// migrateMutes imports mutes from text file into storage set in MUTE_DSN.
+func migrateMutes(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: sendyxmail migrate-mutes <mutes.txt>")
+	}
+	dsn := os.Getenv("MUTE_DSN")
+	if dsn == "" {
+		return errors.New("MUTE_DSN must be provided as env variable")
+	}
+	_, err := os.Stat(args[0])
+	if err != nil {
+		return err
+	}
+	src, err := mutemanager.NewFileStorage(args[0])
+	if err != nil {
+		return err
+	}
+	dst, err := mutemanager.OpenStorage(dsn, "")
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	count, err := mutemanager.Import(dst, src)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("imported %d mutes from %s\n", count, args[0])
+	return nil
+}
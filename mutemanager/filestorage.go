@@ -0,0 +1,446 @@
+package mutemanager
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+// FileStorage stores muted entries in text file, one per line.
+// Timed mutes are stored as entry and expiry time in RFC 3339 separated by tab.
+// File is locked with lock file next to it, so several processes can share it.
+// Every change rereads the file to keep changes made by other processes.
+// History of changes is appended to history file next to it, one JSON event per line.
+// History is read from the end, and the latest event of every entry is kept in memory.
+type FileStorage struct {
+	file    string
+	entries map[string]time.Time
+	// lastEvents are the latest events of entries in the first historySize bytes of history file
+	lastEvents  map[string]Event
+	historySize int64
+	lock        *flock.Flock
+	mutex       sync.Mutex
+}
+
+func NewFileStorage(file string) (*FileStorage, error) {
+	var err error
+	fst := &FileStorage{
+		entries:    map[string]time.Time{},
+		lastEvents: map[string]Event{},
+	}
+	fst.file, err = filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	fst.lock = flock.New(fst.file + ".lock")
+	return fst, nil
+}
+
+func (fst *FileStorage) Load() (map[string]time.Time, error) {
+	unlock, err := fst.lockFile()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	err = fst.loadFile()
+	if err != nil {
+		return nil, err
+	}
+	return maps.Clone(fst.entries), nil
+}
+
+func (fst *FileStorage) Set(entry string, until time.Time) error {
+	unlock, err := fst.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	err = fst.loadFile()
+	if err != nil {
+		return err
+	}
+	previousUntil, previousState := fst.entries[entry]
+	fst.entries[entry] = until
+	err = fst.saveFile()
+	if err != nil {
+		// Roll back
+		if previousState {
+			fst.entries[entry] = previousUntil
+		} else {
+			delete(fst.entries, entry)
+		}
+		return err
+	}
+	return nil
+}
+
+func (fst *FileStorage) Delete(entries ...string) ([]string, error) {
+	unlock, err := fst.lockFile()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	err = fst.loadFile()
+	if err != nil {
+		return nil, err
+	}
+	removed := map[string]time.Time{}
+	for _, entry := range entries {
+		if until, ok := fst.entries[entry]; ok {
+			removed[entry] = until
+			delete(fst.entries, entry)
+		}
+	}
+	if len(removed) == 0 {
+		return []string{}, nil
+	}
+	err = fst.saveFile()
+	if err != nil {
+		maps.Copy(fst.entries, removed)
+		return nil, err
+	}
+	return slices.Collect(maps.Keys(removed)), nil
+}
+
+// Version returns hash of the file content.
+func (fst *FileStorage) Version() (string, error) {
+	unlock, err := fst.rlockFile()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	content, err := os.ReadFile(fst.file)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	hash := fnv.New64a()
+	hash.Write(content)
+	return fmt.Sprintf("%016x", hash.Sum64()), nil
+}
+
+func (fst *FileStorage) AddEvent(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	unlock, err := fst.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	file, err := os.OpenFile(fst.historyFile(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	if err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// History reads history file from the end until limit events are found.
+func (fst *FileStorage) History(entry string, limit int) ([]Event, error) {
+	unlock, err := fst.rlockFile()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	file, err := os.Open(fst.historyFile())
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []Event{}, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	events := []Event{}
+	err = scanLinesBackward(file, info.Size(), func(line []byte) (bool, error) {
+		var event Event
+		err := json.Unmarshal(line, &event)
+		if err != nil {
+			return false, fmt.Errorf("%s has invalid event: %w", fst.historyFile(), err)
+		}
+		if entry == "" || event.Entry == entry {
+			events = append(events, event)
+		}
+		return limit <= 0 || len(events) < limit, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// LastEvents returns the latest events of entries which have history.
+// Without entries it returns the latest events of all muted entries.
+// Only events appended since the previous call are read from history file.
+func (fst *FileStorage) LastEvents(entries ...string) (map[string]Event, error) {
+	lock := fst.rlockFile
+	if len(entries) == 0 {
+		// Mute file is loaded, which creates it if it is missing
+		lock = fst.lockFile
+	}
+	unlock, err := lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	err = fst.readNewEvents()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		err = fst.loadFile()
+		if err != nil {
+			return nil, err
+		}
+		entries = slices.Collect(maps.Keys(fst.entries))
+	}
+	events := map[string]Event{}
+	for _, entry := range entries {
+		if event, ok := fst.lastEvents[entry]; ok {
+			events[entry] = event
+		}
+	}
+	return events, nil
+}
+
+// readNewEvents updates lastEvents with events appended to history file since it was read last time.
+func (fst *FileStorage) readNewEvents() error {
+	file, err := os.Open(fst.historyFile())
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			fst.lastEvents = map[string]Event{}
+			fst.historySize = 0
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < fst.historySize {
+		// History file was truncated, read it again
+		fst.lastEvents = map[string]Event{}
+		fst.historySize = 0
+	}
+	_, err = file.Seek(fst.historySize, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Incomplete line is read again next time
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fst.historySize += int64(len(line))
+		var event Event
+		err = json.Unmarshal(line, &event)
+		if err != nil {
+			return fmt.Errorf("%s has invalid event: %w", fst.historyFile(), err)
+		}
+		fst.lastEvents[event.Entry] = event
+	}
+}
+
+func (fst *FileStorage) historyFile() string {
+	return fst.file + ".history"
+}
+
+func (fst *FileStorage) Close() error {
+	return nil
+}
+
+// rlockFile locks the file for other goroutines and for writing by other processes.
+func (fst *FileStorage) rlockFile() (unlock func(), err error) {
+	fst.mutex.Lock()
+	err = fst.lock.RLock()
+	if err != nil {
+		fst.mutex.Unlock()
+		return nil, err
+	}
+	return func() {
+		fst.lock.Unlock()
+		fst.mutex.Unlock()
+	}, nil
+}
+
+// lockFile locks the file for other goroutines and processes.
+func (fst *FileStorage) lockFile() (unlock func(), err error) {
+	fst.mutex.Lock()
+	err = fst.lock.Lock()
+	if err != nil {
+		fst.mutex.Unlock()
+		return nil, err
+	}
+	return func() {
+		fst.lock.Unlock()
+		fst.mutex.Unlock()
+	}, nil
+}
+
+func (fst *FileStorage) saveFile() error {
+	err := createCopy(fst.file, fst.file+".mmbak")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	tempFile, err := os.CreateTemp(filepath.Dir(fst.file), filepath.Base(fst.file)+".*.mmtemp")
+	if err != nil {
+		return err
+	}
+	defer tempFile.Close()
+	defer os.Remove(tempFile.Name())
+
+	writer := bufio.NewWriter(tempFile)
+
+	for k, until := range fst.entries {
+		line := k
+		if !until.IsZero() {
+			line += "\t" + until.UTC().Format(time.RFC3339)
+		}
+		_, err = writer.WriteString(line + "\n")
+		if err != nil {
+			return err
+		}
+	}
+	err = writer.Flush()
+	if err != nil {
+		return err
+	}
+	err = tempFile.Sync()
+	if err != nil {
+		return err
+	}
+	err = tempFile.Close()
+	if err != nil {
+		return err
+	}
+	err = createCopy(tempFile.Name(), fst.file)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (fst *FileStorage) loadFile() error {
+	file, err := os.Open(fst.file)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		err = fst.saveFile()
+		if err != nil {
+			return err
+		}
+		file, err = os.Open(fst.file)
+		if err != nil {
+			return err
+		}
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	lines := map[string]time.Time{}
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		entry, expiry, timed := strings.Cut(scanner.Text(), "\t")
+		var until time.Time
+		if timed {
+			until, err = time.Parse(time.RFC3339, expiry)
+			if err != nil {
+				return fmt.Errorf("line %d of %s has invalid mute expiry: %w", lineNumber, fst.file, err)
+			}
+		}
+		lines[entry] = until
+	}
+	err = scanner.Err()
+	if err != nil {
+		return err
+	}
+	fst.entries = lines
+	return nil
+}
+
+// scanLinesBackward calls fn for not empty lines of the first size bytes of file,
+// from the last line to the first one, until fn returns false.
+func scanLinesBackward(file *os.File, size int64, fn func(line []byte) (bool, error)) error {
+	const chunkSize = 64 * 1024
+	var rest []byte
+	for offset := size; offset > 0; {
+		length := min(chunkSize, offset)
+		offset -= length
+		chunk := make([]byte, length, length+int64(len(rest)))
+		_, err := file.ReadAt(chunk, offset)
+		if err != nil {
+			return err
+		}
+		lines := bytes.Split(append(chunk, rest...), []byte{'\n'})
+		// The first line may continue in the previous chunk
+		rest = lines[0]
+		for idx := len(lines) - 1; idx > 0; idx-- {
+			if len(lines[idx]) == 0 {
+				continue
+			}
+			more, err := fn(lines[idx])
+			if err != nil || !more {
+				return err
+			}
+		}
+	}
+	if len(rest) == 0 {
+		return nil
+	}
+	_, err := fn(rest)
+	return err
+}
+
+func createCopy(src, dst string) error {
+	if !filepath.IsAbs(src) || !filepath.IsAbs(dst) {
+		return fmt.Errorf("path names MUST be absolute")
+	}
+	sourceFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+
+	destFile, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer destFile.Close()
+
+	_, err = io.Copy(destFile, sourceFile)
+	if err != nil {
+		return err
+	}
+	err = destFile.Sync()
+	return err
+}
@@ -0,0 +1,136 @@
+package mutemanager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileStorageHistory(t *testing.T) {
+	fst, err := NewFileStorage(filepath.Join(t.TempDir(), "mutes.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	// Long reasons make history span several chunks of scanLinesBackward
+	reason := strings.Repeat("x", 1000)
+	for idx := 0; idx < 300; idx++ {
+		entry := fmt.Sprintf("chat-%d", idx%3)
+		err = fst.AddEvent(Event{
+			Entry:  entry,
+			Muted:  idx%2 == 0,
+			Time:   start.Add(time.Duration(idx) * time.Minute),
+			Origin: Origin{Source: SourceChat, Reason: fmt.Sprintf("%d %s", idx, reason)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		entry string
+		limit int
+		want  []int
+	}{
+		{"", 3, []int{299, 298, 297}},
+		{"chat-1", 2, []int{298, 295}},
+		{"chat-0", 0, nil},
+		{"missing", 5, []int{}},
+	}
+	for _, tt := range tests {
+		events, err := fst.History(tt.entry, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.want == nil {
+			if len(events) != 100 {
+				t.Errorf("History(%q, %d) returned %d events, want 100", tt.entry, tt.limit, len(events))
+			}
+			continue
+		}
+		got := []int{}
+		for _, event := range events {
+			got = append(got, int(event.Time.Sub(start)/time.Minute))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("History(%q, %d) = events %v, want %v", tt.entry, tt.limit, got, tt.want)
+		}
+	}
+
+	last, err := fst.LastEvents("chat-0", "chat-2", "missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(last) != 2 || !last["chat-0"].Time.Equal(start.Add(297*time.Minute)) || !last["chat-2"].Time.Equal(start.Add(299*time.Minute)) {
+		t.Errorf("LastEvents() = %v", last)
+	}
+
+	err = fst.Set("chat-1", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, err = fst.LastEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(last) != 1 || !last["chat-1"].Time.Equal(start.Add(298*time.Minute)) {
+		t.Errorf("LastEvents() of muted entries = %v", last)
+	}
+
+	// Event appended by another process is read on the next call
+	other, err := NewFileStorage(fst.file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = other.AddEvent(Event{Entry: "chat-0", Muted: true, Time: start.Add(time.Hour * 24)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, err = fst.LastEvents("chat-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !last["chat-0"].Time.Equal(start.Add(time.Hour * 24)) {
+		t.Errorf("LastEvents() after append = %v", last)
+	}
+
+	err = os.Remove(fst.historyFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, err = fst.LastEvents("chat-0")
+	if err != nil || len(last) != 0 {
+		t.Errorf("LastEvents() after removing history = %v, %v", last, err)
+	}
+}
+
+func TestRemoveExpiredRecordsEvent(t *testing.T) {
+	fst, err := NewFileStorage(filepath.Join(t.TempDir(), "mutes.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	until := time.Now().Add(-time.Minute).Truncate(time.Second)
+	err = fst.Set("chat-0", until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mm, err := New(fst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mm.removeExpired()
+
+	event, ok := mm.LastEvent("chat-0")
+	if !ok {
+		t.Fatal("no event recorded for expired mute")
+	}
+	if event.Muted || event.Source != SourceSystem || !event.Time.Equal(until) {
+		t.Errorf("LastEvent() = %+v, want system unmute at %s", event, until)
+	}
+	if mm.GetMute("chat-0") {
+		t.Error("expired mute is still muted")
+	}
+}
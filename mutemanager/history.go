@@ -0,0 +1,62 @@
+package mutemanager
+
+import "time"
+
+// Sources of mute changes
+const (
+	SourceChat   = "chat"
+	SourceAPI    = "api"
+	SourceSystem = "system"
+)
+
+// Origin describes who changed mute, from where and why.
+type Origin struct {
+	Source    string `json:"source"`
+	ActorHuid string `json:"actor_huid,omitempty"`
+	ActorName string `json:"actor_name,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Event is one mute or unmute of entry.
+type Event struct {
+	Entry string     `json:"entry"`
+	Muted bool       `json:"muted"`
+	Until *time.Time `json:"until,omitempty"`
+	Time  time.Time  `json:"time"`
+	Origin
+}
+
+// History returns at most limit latest events of entry, newest first.
+// Empty entry returns events of all entries.
+func (mm *MuteManager) History(entry string, limit int) ([]Event, error) {
+	return mm.storage.History(entry, limit)
+}
+
+// LastEvents returns the latest events of all muted entries which have history.
+func (mm *MuteManager) LastEvents() (map[string]Event, error) {
+	return mm.storage.LastEvents()
+}
+
+// LastEvent returns latest event of entry.
+func (mm *MuteManager) LastEvent(entry string) (Event, bool) {
+	events, err := mm.storage.LastEvents(entry)
+	if err != nil {
+		return Event{}, false
+	}
+	event, ok := events[entry]
+	return event, ok
+}
+
+func newEvent(entry string, state bool, until time.Time, origin Origin) Event {
+	event := Event{
+		Entry:  entry,
+		Muted:  state,
+		Time:   time.Now().UTC(),
+		Origin: origin,
+	}
+	if state && !until.IsZero() {
+		until = until.UTC()
+		event.Until = &until
+	}
+	return event
+}
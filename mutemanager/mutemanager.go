@@ -1,181 +1,231 @@
 package mutemanager
 
 import (
-	"bufio"
-	"errors"
 	"fmt"
-	"io"
-	"io/fs"
-	"os"
-	"path/filepath"
+	"log"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 )
 
+// How often storage is checked for changes made by other processes
+const refreshInterval = 5 * time.Second
+
+// MuteManager keeps muted entries in memory and persists them in Storage.
+// Entries are reloaded when storage version changes, so several replicas can share one storage.
 type MuteManager struct {
-	file         string
-	mutedEntries map[string]bool
+	storage      Storage
+	version      string
+	mutedEntries map[string]time.Time
 	mutex        sync.RWMutex
 }
 
-func New(file string) (*MuteManager, error) {
-	var err error
+func New(storage Storage) (*MuteManager, error) {
 	mm := &MuteManager{
-		mutedEntries: map[string]bool{},
-		file:         file,
+		storage: storage,
 	}
-	mm.file, err = filepath.Abs(mm.file)
+	err := mm.reload()
 	if err != nil {
 		return nil, err
 	}
-	err = mm.loadFile()
-	if err != nil {
-		return nil, err
+	go func() {
+		for {
+			time.Sleep(time.Minute)
+			mm.removeExpired()
+		}
+	}()
+	go func() {
+		for {
+			time.Sleep(refreshInterval)
+			mm.refresh()
+		}
+	}()
+	return mm, nil
+}
+
+// SenderEntry is entry of one sender muted in one chat.
+func SenderEntry(chat string, sender string) string {
+	return chat + "/" + sender
+}
+
+// MutedSenders returns senders muted in chat and expiry of their mutes.
+func (mm *MuteManager) MutedSenders(chat string) map[string]time.Time {
+	mm.mutex.RLock()
+	defer mm.mutex.RUnlock()
+	now := time.Now()
+	senders := map[string]time.Time{}
+	for entry, until := range mm.mutedEntries {
+		sender, ok := strings.CutPrefix(entry, chat+"/")
+		if ok && !expired(until, now) {
+			senders[sender] = until
+		}
 	}
-	return mm, err
+	return senders
 }
 
-func (mm *MuteManager) GetMute(entry string) bool {
-	var state bool
+// Entries returns all muted entries and expiry of their mutes.
+func (mm *MuteManager) Entries() map[string]time.Time {
 	mm.mutex.RLock()
 	defer mm.mutex.RUnlock()
-	_, state = mm.mutedEntries[entry]
+	now := time.Now()
+	entries := map[string]time.Time{}
+	for entry, until := range mm.mutedEntries {
+		if !expired(until, now) {
+			entries[entry] = until
+		}
+	}
+	return entries
+}
+
+func (mm *MuteManager) GetMute(entry string) bool {
+	_, state := mm.MutedUntil(entry)
 	return state
 }
 
-func (mm *MuteManager) SetMute(entry string, state bool) (changed bool, err error) {
+// MutedUntil reports if entry is muted and when mute expires.
+// Zero time means that entry is muted until it is unmuted explicitly.
+func (mm *MuteManager) MutedUntil(entry string) (until time.Time, muted bool) {
 	mm.mutex.RLock()
-	_, currentState := mm.mutedEntries[entry]
-	mm.mutex.RUnlock()
-	if currentState == state {
-		return false, nil
+	defer mm.mutex.RUnlock()
+	until, muted = mm.mutedEntries[entry]
+	if muted && expired(until, time.Now()) {
+		return time.Time{}, false
 	}
+	return until, muted
+}
+
+// SetMute mutes entry until it is unmuted explicitly or unmutes it.
+// Change is recorded in history with origin.
+func (mm *MuteManager) SetMute(entry string, state bool, origin Origin) (changed bool, err error) {
+	return mm.setEntry(entry, state, time.Time{}, origin)
+}
+
+// SetMuteUntil mutes entry until given time. Zero time mutes entry until it is unmuted explicitly.
+// Existing mute is replaced with the new expiry. Change is recorded in history with origin.
+func (mm *MuteManager) SetMuteUntil(entry string, until time.Time, origin Origin) (changed bool, err error) {
+	return mm.setEntry(entry, true, until, origin)
+}
+
+func (mm *MuteManager) setEntry(entry string, state bool, until time.Time, origin Origin) (changed bool, err error) {
 	mm.mutex.Lock()
 	defer mm.mutex.Unlock()
 
-	// Check again
-	_, currentState = mm.mutedEntries[entry]
-	if currentState == state {
+	previousUntil, previousState := mm.mutedEntries[entry]
+	currentState := previousState && !expired(previousUntil, time.Now())
+	if currentState == state && (!state || previousUntil.Equal(until)) {
 		return false, nil
 	}
 
 	if state {
-		mm.mutedEntries[entry] = true
+		err = mm.storage.Set(entry, until)
 	} else {
-		delete(mm.mutedEntries, entry)
+		_, err = mm.storage.Delete(entry)
 	}
-
-	err = mm.saveFile()
 	if err != nil {
-		// Roll back
-		if state {
-			delete(mm.mutedEntries, entry)
-		} else {
-			mm.mutedEntries[entry] = true
-		}
 		return false, err
 	}
-	return true, nil
-}
-
-func (mm *MuteManager) saveFile() error {
 
-	err := mm.createCopy(mm.file, mm.file+".mmbak")
-	if err != nil {
-		return err
+	if state {
+		mm.mutedEntries[entry] = until
+	} else {
+		delete(mm.mutedEntries, entry)
 	}
-	tempFile, err := os.CreateTemp(filepath.Dir(mm.file), filepath.Base(mm.file)+".*.mmtemp")
+	// Mute is already changed, so failure to record history is only logged
+	err = mm.storage.AddEvent(newEvent(entry, state, until, origin))
 	if err != nil {
-		return err
+		log.Printf("failed to record mute history of %s: %s", entry, err.Error())
 	}
-	defer tempFile.Close()
-	defer os.Remove(tempFile.Name())
-
-	writer := bufio.NewWriter(tempFile)
+	return true, nil
+}
 
-	for k := range mm.mutedEntries {
-		_, err = writer.WriteString(k + "\n")
-		if err != nil {
-			return err
-		}
-	}
-	err = writer.Flush()
+// refresh reloads entries if they were changed in storage.
+func (mm *MuteManager) refresh() {
+	version, err := mm.storage.Version()
 	if err != nil {
-		return err
+		log.Printf("failed to check mutes version: %s", err.Error())
+		return
+	}
+	mm.mutex.RLock()
+	changed := version != mm.version
+	mm.mutex.RUnlock()
+	if !changed {
+		return
 	}
-	err = tempFile.Sync()
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+	err = mm.reload()
 	if err != nil {
-		return err
+		log.Printf("failed to reload mutes: %s", err.Error())
 	}
-	err = tempFile.Close()
+}
+
+// reload reads version and entries from storage. Version is read first,
+// so change made between reads is reloaded on the next refresh.
+func (mm *MuteManager) reload() error {
+	version, err := mm.storage.Version()
 	if err != nil {
 		return err
 	}
-	err = mm.createCopy(tempFile.Name(), mm.file)
+	entries, err := mm.storage.Load()
 	if err != nil {
 		return err
 	}
+	mm.version = version
+	mm.mutedEntries = entries
 	return nil
 }
 
-func (mm *MuteManager) loadFile() error {
-	var file *os.File
-	var err error
-	file, err = os.Open(mm.file)
-	if err != nil {
-		if errors.Is(err, fs.ErrNotExist) {
-			err = mm.saveFile()
-			if err != nil {
-				return err
-			}
-			file, err = os.Open(mm.file)
-			if err != nil {
-				return err
-			}
-		} else {
-			return err
+func expired(until time.Time, now time.Time) bool {
+	return !until.IsZero() && !now.Before(until)
+}
+
+func (mm *MuteManager) removeExpired() {
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+	now := time.Now()
+	removed := map[string]time.Time{}
+	for entry, until := range mm.mutedEntries {
+		if expired(until, now) {
+			removed[entry] = until
 		}
 	}
-	defer file.Close()
-	scanner := bufio.NewScanner(file)
-	lines := map[string]bool{}
-	for scanner.Scan() {
-		lines[scanner.Text()] = true
+	if len(removed) == 0 {
+		return
 	}
-	err = scanner.Err()
+	deleted, err := mm.storage.Delete(slices.Collect(maps.Keys(removed))...)
 	if err != nil {
-		return err
+		log.Printf("failed to remove expired mutes: %s", err.Error())
+		return
 	}
-	mm.mutex.Lock()
-	defer mm.mutex.Unlock()
-	for k := range mm.mutedEntries {
-		delete(mm.mutedEntries, k)
+	for entry := range removed {
+		delete(mm.mutedEntries, entry)
 	}
-	for k := range lines {
-		mm.mutedEntries[k] = true
+	// Mutes removed by other replicas are recorded by them
+	for _, entry := range deleted {
+		event := newEvent(entry, false, time.Time{}, Origin{Source: SourceSystem, Reason: "expired"})
+		event.Time = removed[entry].UTC()
+		err = mm.storage.AddEvent(event)
+		if err != nil {
+			log.Printf("failed to record mute history of %s: %s", entry, err.Error())
+		}
 	}
-	return nil
+	log.Printf("removed %d expired mutes", len(removed))
 }
 
-func (mm *MuteManager) createCopy(src, dst string) error {
-	if !filepath.IsAbs(src) || !filepath.IsAbs(dst) {
-		return fmt.Errorf("path names MUST be absolute")
-	}
-	sourceFile, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer sourceFile.Close()
-
-	destFile, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer destFile.Close()
+// MutedError reports that delivery is blocked by muted entry.
+type MutedError struct {
+	Entry string
+	// Until is zero if entry is muted until it is unmuted explicitly
+	Until time.Time
+	// Sender is set if only this sender is muted in chat
+	Sender string
+}
 
-	_, err = io.Copy(destFile, sourceFile)
-	if err != nil {
-		return err
+func (e *MutedError) Error() string {
+	if e.Sender != "" {
+		return fmt.Sprintf("sender '%s' is muted in this chat", e.Sender)
 	}
-	err = destFile.Sync()
-	return err
+	return "bot is muted in this chat"
 }
@@ -0,0 +1,264 @@
+package mutemanager
+
+import (
+	"database/sql"
+	"math"
+	"strconv"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type sqlDialect struct {
+	driver            string
+	createTable       string
+	selectAll         string
+	upsert            string
+	delete            string
+	createEventsTable string
+	insertEvent       string
+	selectEvents      string
+	selectEntryEvents string
+}
+
+// Version of mutes table is stored in mutes_version table and incremented on every change.
+const (
+	createVersionTable = `CREATE TABLE IF NOT EXISTS mutes_version (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		version BIGINT NOT NULL
+	)`
+	insertVersion    = `INSERT INTO mutes_version (id, version) VALUES (1, 0) ON CONFLICT (id) DO NOTHING`
+	selectVersion    = `SELECT version FROM mutes_version WHERE id = 1`
+	incrementVersion = `UPDATE mutes_version SET version = version + 1 WHERE id = 1`
+)
+
+const createEventsIndex = `CREATE INDEX IF NOT EXISTS mute_events_entry ON mute_events (entry, id)`
+
+const eventColumns = `entry, muted, muted_until, event_time, source, actor_huid, actor_name, reason`
+
+// selectLastEvents selects the latest events of all muted entries, same for both dialects.
+const selectLastEvents = `SELECT ` + eventColumns + ` FROM mute_events WHERE id IN (
+		SELECT MAX(id) FROM mute_events WHERE entry IN (SELECT entry FROM mutes) GROUP BY entry
+	)`
+
+var sqliteDialect = sqlDialect{
+	driver: "sqlite3",
+	createTable: `CREATE TABLE IF NOT EXISTS mutes (
+		entry TEXT PRIMARY KEY,
+		muted_until TIMESTAMP NULL
+	)`,
+	selectAll: `SELECT entry, muted_until FROM mutes`,
+	upsert:    `INSERT INTO mutes (entry, muted_until) VALUES (?, ?) ON CONFLICT (entry) DO UPDATE SET muted_until = excluded.muted_until`,
+	delete:    `DELETE FROM mutes WHERE entry = ?`,
+	createEventsTable: `CREATE TABLE IF NOT EXISTS mute_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		entry TEXT NOT NULL,
+		muted BOOLEAN NOT NULL,
+		muted_until TIMESTAMP NULL,
+		event_time TIMESTAMP NOT NULL,
+		source TEXT NOT NULL,
+		actor_huid TEXT NOT NULL,
+		actor_name TEXT NOT NULL,
+		reason TEXT NOT NULL
+	)`,
+	insertEvent:       `INSERT INTO mute_events (entry, muted, muted_until, event_time, source, actor_huid, actor_name, reason) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+	selectEvents:      `SELECT ` + eventColumns + ` FROM mute_events ORDER BY id DESC LIMIT ?`,
+	selectEntryEvents: `SELECT ` + eventColumns + ` FROM mute_events WHERE entry = ? ORDER BY id DESC LIMIT ?`,
+}
+
+var postgresDialect = sqlDialect{
+	driver: "postgres",
+	createTable: `CREATE TABLE IF NOT EXISTS mutes (
+		entry TEXT PRIMARY KEY,
+		muted_until TIMESTAMPTZ NULL
+	)`,
+	selectAll: `SELECT entry, muted_until FROM mutes`,
+	upsert:    `INSERT INTO mutes (entry, muted_until) VALUES ($1, $2) ON CONFLICT (entry) DO UPDATE SET muted_until = excluded.muted_until`,
+	delete:    `DELETE FROM mutes WHERE entry = $1`,
+	createEventsTable: `CREATE TABLE IF NOT EXISTS mute_events (
+		id BIGSERIAL PRIMARY KEY,
+		entry TEXT NOT NULL,
+		muted BOOLEAN NOT NULL,
+		muted_until TIMESTAMPTZ NULL,
+		event_time TIMESTAMPTZ NOT NULL,
+		source TEXT NOT NULL,
+		actor_huid TEXT NOT NULL,
+		actor_name TEXT NOT NULL,
+		reason TEXT NOT NULL
+	)`,
+	insertEvent:       `INSERT INTO mute_events (entry, muted, muted_until, event_time, source, actor_huid, actor_name, reason) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+	selectEvents:      `SELECT ` + eventColumns + ` FROM mute_events ORDER BY id DESC LIMIT $1`,
+	selectEntryEvents: `SELECT ` + eventColumns + ` FROM mute_events WHERE entry = $1 ORDER BY id DESC LIMIT $2`,
+}
+
+// SQLStorage stores muted entries in mutes table of SQLite or PostgreSQL database.
+// Mutes without expiry have NULL in muted_until.
+type SQLStorage struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// openSQLStorage connects to database and creates tables if they do not exist.
+func openSQLStorage(dialect sqlDialect, dsn string) (*SQLStorage, error) {
+	db, err := sql.Open(dialect.driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if dialect.driver == sqliteDialect.driver {
+		// SQLite allows only one writer
+		db.SetMaxOpenConns(1)
+	}
+	for _, statement := range []string{dialect.createTable, createVersionTable, insertVersion, dialect.createEventsTable, createEventsIndex} {
+		_, err = db.Exec(statement)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &SQLStorage{db: db, dialect: dialect}, nil
+}
+
+func (sst *SQLStorage) Load() (map[string]time.Time, error) {
+	rows, err := sst.db.Query(sst.dialect.selectAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := map[string]time.Time{}
+	for rows.Next() {
+		var entry string
+		var until sql.NullTime
+		err = rows.Scan(&entry, &until)
+		if err != nil {
+			return nil, err
+		}
+		entries[entry] = until.Time
+	}
+	return entries, rows.Err()
+}
+
+func (sst *SQLStorage) Set(entry string, until time.Time) error {
+	tx, err := sst.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	value := sql.NullTime{Time: until.UTC(), Valid: !until.IsZero()}
+	_, err = tx.Exec(sst.dialect.upsert, entry, value)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(incrementVersion)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (sst *SQLStorage) Delete(entries ...string) ([]string, error) {
+	tx, err := sst.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	removed := []string{}
+	for _, entry := range entries {
+		result, err := tx.Exec(sst.dialect.delete, entry)
+		if err != nil {
+			return nil, err
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			removed = append(removed, entry)
+		}
+	}
+	_, err = tx.Exec(incrementVersion)
+	if err != nil {
+		return nil, err
+	}
+	return removed, tx.Commit()
+}
+
+func (sst *SQLStorage) Version() (string, error) {
+	var version int64
+	err := sst.db.QueryRow(selectVersion).Scan(&version)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(version, 10), nil
+}
+
+func (sst *SQLStorage) AddEvent(event Event) error {
+	until := sql.NullTime{}
+	if event.Until != nil {
+		until = sql.NullTime{Time: event.Until.UTC(), Valid: true}
+	}
+	_, err := sst.db.Exec(sst.dialect.insertEvent, event.Entry, event.Muted, until, event.Time.UTC(),
+		event.Source, event.ActorHuid, event.ActorName, event.Reason)
+	return err
+}
+
+func (sst *SQLStorage) History(entry string, limit int) ([]Event, error) {
+	if limit <= 0 {
+		limit = math.MaxInt32
+	}
+	if entry == "" {
+		return sst.queryEvents(sst.dialect.selectEvents, limit)
+	}
+	return sst.queryEvents(sst.dialect.selectEntryEvents, entry, limit)
+}
+
+func (sst *SQLStorage) queryEvents(query string, args ...any) ([]Event, error) {
+	rows, err := sst.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	events := []Event{}
+	for rows.Next() {
+		var event Event
+		var until sql.NullTime
+		err = rows.Scan(&event.Entry, &event.Muted, &until, &event.Time,
+			&event.Source, &event.ActorHuid, &event.ActorName, &event.Reason)
+		if err != nil {
+			return nil, err
+		}
+		if until.Valid {
+			event.Until = &until.Time
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (sst *SQLStorage) LastEvents(entries ...string) (map[string]Event, error) {
+	events := map[string]Event{}
+	if len(entries) == 0 {
+		history, err := sst.queryEvents(selectLastEvents)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range history {
+			events[event.Entry] = event
+		}
+		return events, nil
+	}
+	for _, entry := range entries {
+		history, err := sst.History(entry, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(history) > 0 {
+			events[entry] = history[0]
+		}
+	}
+	return events, nil
+}
+
+func (sst *SQLStorage) Close() error {
+	return sst.db.Close()
+}
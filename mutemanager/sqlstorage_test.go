@@ -0,0 +1,170 @@
+package mutemanager
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func openTestSQLStorage(t *testing.T, dsn string) *SQLStorage {
+	t.Helper()
+	sst, err := openSQLStorage(sqliteDialect, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sst.Close() })
+	return sst
+}
+
+func TestSQLStorage(t *testing.T) {
+	until := time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC)
+	later := until.Add(24 * time.Hour)
+	tests := []struct {
+		name   string
+		change func(sst *SQLStorage) error
+		want   map[string]time.Time
+	}{
+		{"set forever", func(sst *SQLStorage) error {
+			return sst.Set("11112222-3333-4444-5555-666677778888", time.Time{})
+		}, map[string]time.Time{
+			"11112222-3333-4444-5555-666677778888": {},
+		}},
+		{"set with expiry", func(sst *SQLStorage) error {
+			return sst.Set("user@example.com", until)
+		}, map[string]time.Time{
+			"11112222-3333-4444-5555-666677778888": {},
+			"user@example.com":                     until,
+		}},
+		{"replace expiry", func(sst *SQLStorage) error {
+			return sst.Set("user@example.com", later)
+		}, map[string]time.Time{
+			"11112222-3333-4444-5555-666677778888": {},
+			"user@example.com":                     later,
+		}},
+		{"delete missing entry", func(sst *SQLStorage) error {
+			_, err := sst.Delete("missing@example.com")
+			return err
+		}, map[string]time.Time{
+			"11112222-3333-4444-5555-666677778888": {},
+			"user@example.com":                     later,
+		}},
+		{"delete entries", func(sst *SQLStorage) error {
+			_, err := sst.Delete("11112222-3333-4444-5555-666677778888", "user@example.com")
+			return err
+		}, map[string]time.Time{}},
+	}
+
+	sst := openTestSQLStorage(t, ":memory:")
+	version, err := sst.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		err := tt.change(sst)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		entries, err := sst.Load()
+		if err != nil {
+			t.Fatalf("%s: Load: %s", tt.name, err)
+		}
+		if !maps.EqualFunc(entries, tt.want, time.Time.Equal) {
+			t.Errorf("%s: Load() = %v, want %v", tt.name, entries, tt.want)
+		}
+		newVersion, err := sst.Version()
+		if err != nil {
+			t.Fatalf("%s: Version: %s", tt.name, err)
+		}
+		if newVersion == version {
+			t.Errorf("%s: Version() did not change", tt.name)
+		}
+		version = newVersion
+	}
+}
+
+func TestSQLStorageSharedDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mutes.db")
+	first := openTestSQLStorage(t, path)
+	second := openTestSQLStorage(t, path)
+
+	version, err := second.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = first.Set("user@example.com", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newVersion, err := second.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newVersion == version {
+		t.Error("Version() of other connection did not change")
+	}
+	entries, err := second.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := entries["user@example.com"]; !ok {
+		t.Errorf("Load() of other connection = %v", entries)
+	}
+
+	err = first.AddEvent(Event{Entry: "user@example.com", Muted: true, Time: time.Now().UTC()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, err := second.LastEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event, ok := last["user@example.com"]; len(last) != 1 || !ok || !event.Muted {
+		t.Errorf("LastEvents() of other connection = %v", last)
+	}
+
+	removed, err := first.Delete("user@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(removed, []string{"user@example.com"}) {
+		t.Errorf("Delete() = %v, want removed entry", removed)
+	}
+	removed, err = second.Delete("user@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 0 {
+		t.Errorf("Delete() of entry removed by other connection = %v", removed)
+	}
+	last, err = second.LastEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(last) != 0 {
+		t.Errorf("LastEvents() without muted entries = %v", last)
+	}
+}
+
+func TestOpenStorage(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		dsn   string
+		valid bool
+	}{
+		{"", true},
+		{"sqlite://" + filepath.Join(dir, "mutes.db"), true},
+		{"sqlite://" + filepath.Join(dir, "other.db") + "?_busy_timeout=100", true},
+		{"mysql://localhost/mutes", false},
+	}
+	for _, tt := range tests {
+		storage, err := OpenStorage(tt.dsn, filepath.Join(dir, "mutes.txt"))
+		if (err == nil) != tt.valid {
+			t.Errorf("OpenStorage(%q) error = %v", tt.dsn, err)
+		}
+		if err == nil {
+			storage.Close()
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package mutemanager
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Storage persists muted entries of MuteManager.
+// Zero expiry means that entry is muted until it is unmuted explicitly.
+type Storage interface {
+	// Load returns all stored entries with their expiry.
+	Load() (map[string]time.Time, error)
+	// Set stores entry or replaces expiry of existing one.
+	Set(entry string, until time.Time) error
+	// Delete removes entries and returns removed ones. Missing entries are ignored.
+	Delete(entries ...string) ([]string, error)
+	// Version returns stamp that changes on every change of stored entries,
+	// including changes made by other processes.
+	Version() (string, error)
+	// AddEvent appends event to mute history.
+	AddEvent(event Event) error
+	// History returns at most limit latest events of entry, newest first.
+	// Empty entry returns events of all entries.
+	History(entry string, limit int) ([]Event, error)
+	// LastEvents returns the latest event of every given entry which has history.
+	// Without entries it returns the latest events of all muted entries.
+	LastEvents(entries ...string) (map[string]Event, error)
+	Close() error
+}
+
+// OpenStorage opens storage by DSN.
+// Empty DSN means file storage in file, "sqlite://<path>" means SQLite database
+// and "postgres://..." or "postgresql://..." means PostgreSQL database.
+func OpenStorage(dsn string, file string) (Storage, error) {
+	switch {
+	case dsn == "":
+		return NewFileStorage(file)
+	case strings.HasPrefix(dsn, "sqlite://"):
+		path := strings.TrimPrefix(dsn, "sqlite://")
+		if !strings.Contains(path, "_busy_timeout") {
+			// Wait for other processes instead of failing with "database is locked"
+			separator := "?"
+			if strings.Contains(path, "?") {
+				separator = "&"
+			}
+			path += separator + "_busy_timeout=5000"
+		}
+		return openSQLStorage(sqliteDialect, path)
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+		return openSQLStorage(postgresDialect, dsn)
+	}
+	return nil, fmt.Errorf("unsupported mute storage DSN, expected sqlite:// or postgres://")
+}
+
+// Import copies all entries from src to dst and returns number of copied entries.
+// Expired entries are skipped.
+func Import(dst Storage, src Storage) (int, error) {
+	entries, err := src.Load()
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	count := 0
+	for entry, until := range entries {
+		if expired(until, now) {
+			continue
+		}
+		err = dst.Set(entry, until)
+		if err != nil {
+			return count, fmt.Errorf("failed to import %s: %w", entry, err)
+		}
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"sendyxmail/apiv0"
+	"sendyxmail/rotationmanager"
+	"strings"
+	"time"
+
+	"github.com/go-botx/botx"
+	"github.com/go-botx/botx/models"
+)
+
+// handleOnCall shows who is on call now and who is next.
+// Without arguments all rotations are shown.
+func handleOnCall(b *botx.Bot, req *models.CommandRequest, args string) {
+	locale := req.From.Locale
+	rotations := []rotationmanager.Rotation{}
+	if name := strings.TrimSpace(args); name != "" {
+		rotation, ok := rotm.Get(name)
+		if ok {
+			rotations = append(rotations, rotation)
+		}
+	} else {
+		rotations = rotm.List()
+	}
+
+	var text string
+	if len(rotations) == 0 {
+		text = getLocalizedMessage(locale, "rotation_not_found")
+	} else {
+		now := time.Now()
+		parts := []string{}
+		for _, rotation := range rotations {
+			current, next := rotation.OnCall(now)
+			parts = append(parts, fmt.Sprintf(getLocalizedMessage(locale, "oncall"),
+				rotation.Name,
+				apiv0.RotationAddressPrefix+rotation.Name+rotationMailSuffix,
+				current.Mail, current.End.Format(time.DateTime+" MST"),
+				next.Mail, next.Start.Format(time.DateTime+" MST")))
+		}
+		text = strings.Join(parts, "\n\n")
+	}
+
+	message, err := models.NewNDRequest(req.From.GroupChatId, text)
+	if err != nil {
+		return
+	}
+	b.SendMessageAsync(message)
+}
@@ -0,0 +1,121 @@
+package queuemanager
+
+import (
+	"encoding/json"
+	"log"
+	"sendyxmail/filestore"
+	"sync"
+	"time"
+)
+
+// MaxQueued is the maximum number of messages queued for one muted entry.
+// The oldest messages are dropped when it is exceeded.
+const MaxQueued = 100
+
+// Message is a message waiting for muted entry to be unmuted.
+// Payload is opaque for QueueManager.
+type Message struct {
+	Payload  json.RawMessage `json:"payload"`
+	QueuedAt time.Time       `json:"queued_at"`
+}
+
+// IsMutedFunc reports if entry is still muted.
+type IsMutedFunc func(entry string) bool
+
+// DeliverFunc delivers one queued message after entry is unmuted.
+// Message and the following messages of entry are kept and retried later if it returns error.
+type DeliverFunc func(entry string, message Message) error
+
+// QueueManager keeps messages sent to muted entries and delivers them when entries are unmuted.
+type QueueManager struct {
+	file    string
+	queued  map[string][]Message
+	isMuted IsMutedFunc
+	deliver DeliverFunc
+	mutex   sync.RWMutex
+}
+
+// Run loads queued messages from file and starts delivering messages of unmuted entries.
+func Run(file string, isMuted IsMutedFunc, deliver DeliverFunc) (*QueueManager, error) {
+	qum := &QueueManager{
+		file:    file,
+		queued:  map[string][]Message{},
+		isMuted: isMuted,
+		deliver: deliver,
+	}
+	err := filestore.LoadJSON(qum.file, &qum.queued)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			time.Sleep(10 * time.Second)
+			qum.flush()
+		}
+	}()
+	return qum, nil
+}
+
+// Add queues message until entry is unmuted and returns number of messages queued for entry.
+func (qum *QueueManager) Add(entry string, payload json.RawMessage) (int, error) {
+	qum.mutex.Lock()
+	defer qum.mutex.Unlock()
+	previous := qum.queued[entry]
+	messages := append(previous[:len(previous):len(previous)], Message{Payload: payload, QueuedAt: time.Now().UTC()})
+	if len(messages) > MaxQueued {
+		messages = messages[len(messages)-MaxQueued:]
+	}
+	qum.queued[entry] = messages
+	err := qum.save()
+	if err != nil {
+		if previous == nil {
+			delete(qum.queued, entry)
+		} else {
+			qum.queued[entry] = previous
+		}
+		return 0, err
+	}
+	return len(messages), nil
+}
+
+// flush delivers messages of entries which are not muted anymore.
+func (qum *QueueManager) flush() {
+	unmuted := map[string][]Message{}
+	qum.mutex.Lock()
+	for entry, messages := range qum.queued {
+		if !qum.isMuted(entry) {
+			unmuted[entry] = messages
+			delete(qum.queued, entry)
+		}
+	}
+	qum.mutex.Unlock()
+	if len(unmuted) == 0 {
+		return
+	}
+
+	failed := map[string][]Message{}
+	for entry, messages := range unmuted {
+		for i, message := range messages {
+			err := qum.deliver(entry, message)
+			if err != nil {
+				log.Printf("failed to deliver %d queued messages of %s: %s", len(messages)-i, entry, err.Error())
+				failed[entry] = messages[i:]
+				break
+			}
+		}
+	}
+
+	qum.mutex.Lock()
+	defer qum.mutex.Unlock()
+	for entry, messages := range failed {
+		qum.queued[entry] = append(messages, qum.queued[entry]...)
+	}
+	err := qum.save()
+	if err != nil {
+		log.Printf("failed to save queued messages: %s", err.Error())
+	}
+}
+
+func (qum *QueueManager) save() error {
+	return filestore.SaveJSON(qum.file, qum.queued)
+}
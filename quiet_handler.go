@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sendyxmail/quietmanager"
+	"strings"
+
+	"github.com/go-botx/botx"
+	"github.com/go-botx/botx/models"
+)
+
+const quietOff = "off"
+
+// handleQuiet shows, sets or turns off quiet hours of chat.
+func handleQuiet(b *botx.Bot, req *models.CommandRequest) {
+	chatId := req.From.GroupChatId
+	// Time zone names are case sensitive, so arguments are taken from original command body
+	args := strings.Fields(req.Command.Body)[1:]
+
+	text := ""
+	switch {
+	case len(args) == 0:
+		schedule, ok := qm.Get(chatId)
+		if !ok {
+			text = getLocalizedMessage(req.From.Locale, "quiet_none")
+		} else {
+			text = fmt.Sprintf(getLocalizedMessage(req.From.Locale, "quiet_show"), schedule.String())
+		}
+	case len(args) == 1 && strings.EqualFold(args[0], quietOff):
+		removed, err := qm.Remove(chatId)
+		if err != nil {
+			log.Printf("failed to turn off quiet hours of %s: %s", chatId, err.Error())
+			text = getLocalizedMessage(req.From.Locale, "error")
+		} else if !removed {
+			text = getLocalizedMessage(req.From.Locale, "quiet_none")
+		} else {
+			text = getLocalizedMessage(req.From.Locale, "quiet_off")
+		}
+	default:
+		schedule, err := quietmanager.ParseSchedule(chatId, args)
+		if err != nil {
+			text = fmt.Sprintf(getLocalizedMessage(req.From.Locale, "quiet_usage"), err.Error())
+			break
+		}
+		err = qm.Set(schedule)
+		if err != nil {
+			log.Printf("failed to set quiet hours of %s: %s", chatId, err.Error())
+			text = getLocalizedMessage(req.From.Locale, "error")
+		} else {
+			text = fmt.Sprintf(getLocalizedMessage(req.From.Locale, "quiet_set"), schedule.String())
+		}
+	}
+
+	message, err := models.NewNDRequest(chatId, text)
+	if err != nil {
+		return
+	}
+	b.SendMessageAsync(message)
+}
@@ -0,0 +1,188 @@
+package quietmanager
+
+import (
+	"encoding/json"
+	"log"
+	"sendyxmail/filestore"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HeldMessage is a message received during quiet hours.
+// Payload is opaque for QuietManager.
+type HeldMessage struct {
+	Payload    json.RawMessage `json:"payload"`
+	ReceivedAt time.Time       `json:"received_at"`
+}
+
+// DigestFunc delivers messages held in chat after quiet hours end.
+// Messages are held again and retried later if it returns error.
+type DigestFunc func(chatId uuid.UUID, messages []HeldMessage) error
+
+type state struct {
+	Schedules map[uuid.UUID]*Schedule     `json:"schedules"`
+	Held      map[uuid.UUID][]HeldMessage `json:"held"`
+}
+
+type QuietManager struct {
+	file          string
+	state         state
+	deliverDigest DigestFunc
+	mutex         sync.RWMutex
+}
+
+// Run loads schedules and held messages from file and starts delivering digests when quiet hours end.
+func Run(file string, deliverDigest DigestFunc) (*QuietManager, error) {
+	qm := &QuietManager{
+		file: file,
+		state: state{
+			Schedules: map[uuid.UUID]*Schedule{},
+			Held:      map[uuid.UUID][]HeldMessage{},
+		},
+		deliverDigest: deliverDigest,
+	}
+	err := filestore.LoadJSON(qm.file, &qm.state)
+	if err != nil {
+		return nil, err
+	}
+	for chatId, schedule := range qm.state.Schedules {
+		err = schedule.init()
+		if err != nil {
+			log.Printf("ignoring invalid quiet hours of chat %s: %s", chatId, err.Error())
+			delete(qm.state.Schedules, chatId)
+		}
+	}
+	go func() {
+		for {
+			time.Sleep(time.Minute)
+			qm.flush()
+		}
+	}()
+	return qm, nil
+}
+
+// Get returns quiet hours of chat.
+func (qm *QuietManager) Get(chatId uuid.UUID) (Schedule, bool) {
+	qm.mutex.RLock()
+	defer qm.mutex.RUnlock()
+	schedule, ok := qm.state.Schedules[chatId]
+	if !ok {
+		return Schedule{}, false
+	}
+	return *schedule, true
+}
+
+// Set replaces quiet hours of chat.
+func (qm *QuietManager) Set(schedule Schedule) error {
+	qm.mutex.Lock()
+	defer qm.mutex.Unlock()
+	previous, existed := qm.state.Schedules[schedule.ChatId]
+	qm.state.Schedules[schedule.ChatId] = &schedule
+	err := qm.save()
+	if err != nil {
+		if existed {
+			qm.state.Schedules[schedule.ChatId] = previous
+		} else {
+			delete(qm.state.Schedules, schedule.ChatId)
+		}
+		return err
+	}
+	return nil
+}
+
+// Remove turns quiet hours of chat off. Held messages are delivered soon.
+func (qm *QuietManager) Remove(chatId uuid.UUID) (bool, error) {
+	qm.mutex.Lock()
+	defer qm.mutex.Unlock()
+	previous, existed := qm.state.Schedules[chatId]
+	if !existed {
+		return false, nil
+	}
+	delete(qm.state.Schedules, chatId)
+	err := qm.save()
+	if err != nil {
+		qm.state.Schedules[chatId] = previous
+		return false, err
+	}
+	return true, nil
+}
+
+// Hold keeps message if quiet hours of chat are on.
+// It returns false if message must be delivered now.
+func (qm *QuietManager) Hold(chatId uuid.UUID, payload json.RawMessage) (bool, time.Time, error) {
+	qm.mutex.Lock()
+	defer qm.mutex.Unlock()
+	schedule, ok := qm.state.Schedules[chatId]
+	if !ok {
+		return false, time.Time{}, nil
+	}
+	now := time.Now()
+	active, end := schedule.Active(now)
+	if !active {
+		return false, time.Time{}, nil
+	}
+	qm.state.Held[chatId] = append(qm.state.Held[chatId], HeldMessage{Payload: payload, ReceivedAt: now.UTC()})
+	err := qm.save()
+	if err != nil {
+		held := qm.state.Held[chatId]
+		qm.state.Held[chatId] = held[:len(held)-1]
+		return false, time.Time{}, err
+	}
+	return true, end, nil
+}
+
+// HeldUntil reports if message received now would be held and when quiet hours end.
+func (qm *QuietManager) HeldUntil(chatId uuid.UUID) (bool, time.Time) {
+	qm.mutex.RLock()
+	defer qm.mutex.RUnlock()
+	schedule, ok := qm.state.Schedules[chatId]
+	if !ok {
+		return false, time.Time{}
+	}
+	return schedule.Active(time.Now())
+}
+
+// flush delivers digests to chats which quiet hours are over.
+func (qm *QuietManager) flush() {
+	now := time.Now()
+	digests := map[uuid.UUID][]HeldMessage{}
+	qm.mutex.Lock()
+	for chatId, messages := range qm.state.Held {
+		if schedule, ok := qm.state.Schedules[chatId]; ok {
+			if active, _ := schedule.Active(now); active {
+				continue
+			}
+		}
+		digests[chatId] = messages
+		delete(qm.state.Held, chatId)
+	}
+	qm.mutex.Unlock()
+	if len(digests) == 0 {
+		return
+	}
+
+	failed := map[uuid.UUID][]HeldMessage{}
+	for chatId, messages := range digests {
+		err := qm.deliverDigest(chatId, messages)
+		if err != nil {
+			log.Printf("failed to deliver quiet hours digest to %s: %s", chatId, err.Error())
+			failed[chatId] = messages
+		}
+	}
+
+	qm.mutex.Lock()
+	defer qm.mutex.Unlock()
+	for chatId, messages := range failed {
+		qm.state.Held[chatId] = append(messages, qm.state.Held[chatId]...)
+	}
+	err := qm.save()
+	if err != nil {
+		log.Printf("failed to save quiet hours: %s", err.Error())
+	}
+}
+
+func (qm *QuietManager) save() error {
+	return filestore.SaveJSON(qm.file, qm.state)
+}
@@ -0,0 +1,153 @@
+package quietmanager
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	// Docker image has no time zone database
+	_ "time/tzdata"
+
+	"github.com/google/uuid"
+)
+
+// Days of quiet hours schedule.
+const (
+	DaysDaily    = "daily"
+	DaysWeekdays = "weekdays"
+	DaysWeekends = "weekends"
+)
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Schedule is quiet hours of one chat.
+// Window starts at Start on matching days and ends at End, on the next day if End is not after Start.
+type Schedule struct {
+	ChatId   uuid.UUID `json:"chat_id"`
+	Start    string    `json:"start"`
+	End      string    `json:"end"`
+	Location string    `json:"location"`
+	Days     string    `json:"days"`
+
+	start    time.Duration
+	end      time.Duration
+	location *time.Location
+}
+
+// ParseSchedule parses arguments of /quiet command: 22:00-08:00 [Europe/Moscow] [weekdays]
+// Days are daily, weekdays, weekends or comma separated list like mon,wed,fri.
+func ParseSchedule(chatId uuid.UUID, args []string) (Schedule, error) {
+	if len(args) == 0 || len(args) > 3 {
+		return Schedule{}, errors.New("expected window, optional time zone and optional days")
+	}
+	start, end, ok := strings.Cut(args[0], "-")
+	if !ok {
+		return Schedule{}, errors.New("window must look like 22:00-08:00")
+	}
+	schedule := Schedule{
+		ChatId:   chatId,
+		Start:    start,
+		End:      end,
+		Location: time.Local.String(),
+		Days:     DaysDaily,
+	}
+	for _, arg := range args[1:] {
+		if isDays(strings.ToLower(arg)) {
+			schedule.Days = strings.ToLower(arg)
+		} else {
+			schedule.Location = arg
+		}
+	}
+	err := schedule.init()
+	if err != nil {
+		return Schedule{}, err
+	}
+	return schedule, nil
+}
+
+func isDays(value string) bool {
+	switch value {
+	case DaysDaily, DaysWeekdays, DaysWeekends:
+		return true
+	}
+	for _, day := range strings.Split(value, ",") {
+		if !slices.Contains(weekdayNames, day) {
+			return false
+		}
+	}
+	return true
+}
+
+// init validates schedule and prepares it for Active.
+func (s *Schedule) init() error {
+	start, err := parseClock(s.Start)
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(s.End)
+	if err != nil {
+		return err
+	}
+	if !isDays(s.Days) {
+		return fmt.Errorf("unknown days '%s'", s.Days)
+	}
+	s.location, err = time.LoadLocation(s.Location)
+	if err != nil {
+		return fmt.Errorf("unknown time zone '%s'", s.Location)
+	}
+	s.start = start
+	s.end = end
+	return nil
+}
+
+func parseClock(value string) (time.Duration, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("time '%s' must look like 22:00", value)
+	}
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+func (s *Schedule) matchDay(day time.Weekday) bool {
+	switch s.Days {
+	case DaysDaily:
+		return true
+	case DaysWeekdays:
+		return day != time.Saturday && day != time.Sunday
+	case DaysWeekends:
+		return day == time.Saturday || day == time.Sunday
+	}
+	return slices.Contains(strings.Split(s.Days, ","), weekdayNames[day])
+}
+
+// Active reports if quiet hours are on at given time and when they end.
+// Days are matched by the day when window starts.
+// Start and end are wall clock times, so window is shorter or longer when clocks change.
+func (s *Schedule) Active(now time.Time) (bool, time.Time) {
+	local := now.In(s.location)
+	for _, offset := range []int{0, -1} {
+		day := local.Day() + offset
+		if !s.matchDay(time.Date(local.Year(), local.Month(), day, 12, 0, 0, 0, s.location).Weekday()) {
+			continue
+		}
+		start := s.clockOn(local, day, s.start)
+		if s.end <= s.start {
+			day++
+		}
+		end := s.clockOn(local, day, s.end)
+		if !local.Before(start) && local.Before(end) {
+			return true, end
+		}
+	}
+	return false, time.Time{}
+}
+
+// clockOn returns time of day clock on day of month and year of local.
+func (s *Schedule) clockOn(local time.Time, day int, clock time.Duration) time.Time {
+	return time.Date(local.Year(), local.Month(), day, int(clock/time.Hour), int(clock%time.Hour/time.Minute), 0, 0, s.location)
+}
+
+func (s *Schedule) String() string {
+	return fmt.Sprintf("%s-%s %s %s", s.Start, s.End, s.Location, s.Days)
+}
@@ -0,0 +1,79 @@
+package quietmanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestScheduleActive(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		now    string
+		active bool
+		end    string
+	}{
+		{"before overnight window", []string{"22:00-08:00", "Europe/Moscow"}, "2026-10-19T21:59:00+03:00", false, ""},
+		{"overnight window evening", []string{"22:00-08:00", "Europe/Moscow"}, "2026-10-19T23:00:00+03:00", true, "2026-10-20T08:00:00+03:00"},
+		{"overnight window after midnight", []string{"22:00-08:00", "Europe/Moscow"}, "2026-10-20T07:59:00+03:00", true, "2026-10-20T08:00:00+03:00"},
+		{"overnight window end", []string{"22:00-08:00", "Europe/Moscow"}, "2026-10-20T08:00:00+03:00", false, ""},
+		{"time of other zone", []string{"22:00-08:00", "Europe/Moscow"}, "2026-10-19T20:30:00Z", true, "2026-10-20T08:00:00+03:00"},
+		{"weekdays friday night", []string{"22:00-08:00", "Europe/Moscow", "weekdays"}, "2026-10-16T23:00:00+03:00", true, "2026-10-17T08:00:00+03:00"},
+		{"weekdays saturday morning", []string{"22:00-08:00", "Europe/Moscow", "weekdays"}, "2026-10-17T07:00:00+03:00", true, "2026-10-17T08:00:00+03:00"},
+		{"weekdays saturday night", []string{"22:00-08:00", "Europe/Moscow", "weekdays"}, "2026-10-17T23:00:00+03:00", false, ""},
+		{"weekdays monday morning", []string{"22:00-08:00", "Europe/Moscow", "weekdays"}, "2026-10-19T07:00:00+03:00", false, ""},
+		{"weekends sunday morning", []string{"22:00-08:00", "Europe/Moscow", "weekends"}, "2026-10-19T07:00:00+03:00", true, "2026-10-19T08:00:00+03:00"},
+		{"day list matching day", []string{"12:00-14:00", "Europe/Moscow", "mon,wed"}, "2026-10-21T13:00:00+03:00", true, "2026-10-21T14:00:00+03:00"},
+		{"day list other day", []string{"12:00-14:00", "Europe/Moscow", "mon,wed"}, "2026-10-20T13:00:00+03:00", false, ""},
+		{"whole day window", []string{"09:00-09:00", "Europe/Moscow"}, "2026-10-20T08:00:00+03:00", true, "2026-10-20T09:00:00+03:00"},
+		{"clocks forward inside window", []string{"01:00-04:00", "Europe/Berlin"}, "2026-03-29T03:30:00+02:00", true, "2026-03-29T04:00:00+02:00"},
+		{"clocks forward after window", []string{"01:00-04:00", "Europe/Berlin"}, "2026-03-29T04:30:00+02:00", false, ""},
+		{"clocks back inside window", []string{"01:00-04:00", "Europe/Berlin"}, "2026-10-25T03:30:00+01:00", true, "2026-10-25T04:00:00+01:00"},
+		{"clocks back before window", []string{"02:30-04:00", "Europe/Berlin"}, "2026-10-25T01:30:00+02:00", false, ""},
+		{"clocks forward overnight", []string{"22:00-06:00", "Europe/Berlin"}, "2026-03-28T23:00:00+01:00", true, "2026-03-29T06:00:00+02:00"},
+		{"clocks back overnight", []string{"22:00-06:00", "Europe/Berlin"}, "2026-10-25T05:30:00+01:00", true, "2026-10-25T06:00:00+01:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(uuid.New(), tt.args)
+			if err != nil {
+				t.Fatalf("ParseSchedule(%v): %s", tt.args, err)
+			}
+			now, err := time.Parse(time.RFC3339, tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			active, end := schedule.Active(now)
+			if active != tt.active {
+				t.Fatalf("Active(%s) = %t, want %t", tt.now, active, tt.active)
+			}
+			if !tt.active {
+				return
+			}
+			wantEnd, err := time.Parse(time.RFC3339, tt.end)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !end.Equal(wantEnd) {
+				t.Errorf("Active(%s) ends at %s, want %s", tt.now, end.Format(time.RFC3339), tt.end)
+			}
+		})
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"22:00"},
+		{"25:00-08:00"},
+		{"22:00-08:00", "Mars/Olympus"},
+		{"22:00-08:00", "Europe/Moscow", "mon,xyz"},
+	}
+	for _, args := range tests {
+		if _, err := ParseSchedule(uuid.New(), args); err == nil {
+			t.Errorf("ParseSchedule(%v) succeeded, want error", args)
+		}
+	}
+}
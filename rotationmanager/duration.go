@@ -0,0 +1,25 @@
+package rotationmanager
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Duration is time.Duration stored in JSON as string like "168h".
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+	d.Duration, err = time.ParseDuration(value)
+	return err
+}
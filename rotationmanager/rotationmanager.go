@@ -0,0 +1,218 @@
+package rotationmanager
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sendyxmail/filestore"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rotation is on-call schedule.
+// Members take shifts of ShiftLength in turn, first shift starts at Handover.
+type Rotation struct {
+	Name        string     `json:"name"`
+	Members     []string   `json:"members"`
+	ShiftLength Duration   `json:"shift_length"`
+	Handover    time.Time  `json:"handover"`
+	Overrides   []Override `json:"overrides,omitempty"`
+}
+
+// Override replaces scheduled member between Start and End.
+type Override struct {
+	Mail  string    `json:"mail"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Shift is a period when member is on call.
+type Shift struct {
+	Mail  string    `json:"mail"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type RotationManager struct {
+	file      string
+	rotations map[string]*Rotation
+	mutex     sync.RWMutex
+}
+
+var (
+	ErrNotFound = errors.New("rotation not found")
+	namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+)
+
+func New(file string) (*RotationManager, error) {
+	rm := &RotationManager{
+		file:      file,
+		rotations: map[string]*Rotation{},
+	}
+	err := filestore.LoadJSON(rm.file, &rm.rotations)
+	if err != nil {
+		return nil, err
+	}
+	return rm, nil
+}
+
+// Validate checks rotation and normalizes member mails.
+func (r *Rotation) Validate() error {
+	r.Name = strings.ToLower(strings.TrimSpace(r.Name))
+	if !namePattern.MatchString(r.Name) {
+		return fmt.Errorf("rotation name '%s' must contain only lowercase letters, digits, '.', '_' and '-'", r.Name)
+	}
+	if len(r.Members) == 0 {
+		return errors.New("rotation must have members")
+	}
+	for idx := range r.Members {
+		r.Members[idx] = strings.ToLower(strings.TrimSpace(r.Members[idx]))
+	}
+	if r.ShiftLength.Duration < time.Hour {
+		return errors.New("shift_length must be at least 1h")
+	}
+	if r.Handover.IsZero() {
+		return errors.New("handover time must be set")
+	}
+	for idx := range r.Overrides {
+		r.Overrides[idx].Mail = strings.ToLower(strings.TrimSpace(r.Overrides[idx].Mail))
+		if r.Overrides[idx].Mail == "" || !r.Overrides[idx].End.After(r.Overrides[idx].Start) {
+			return fmt.Errorf("override number %d must have mail and end after start", idx+1)
+		}
+	}
+	return nil
+}
+
+// ShiftAt returns scheduled shift that contains moment at, including overrides.
+func (r *Rotation) ShiftAt(at time.Time) Shift {
+	for _, override := range r.Overrides {
+		if !at.Before(override.Start) && at.Before(override.End) {
+			return Shift(override)
+		}
+	}
+	shift := r.regularShiftAt(at)
+	// Override may start or end in the middle of regular shift
+	for _, override := range r.Overrides {
+		if override.Start.After(at) && override.Start.Before(shift.End) {
+			shift.End = override.Start
+		}
+		if !override.End.After(at) && override.End.After(shift.Start) {
+			shift.Start = override.End
+		}
+	}
+	return shift
+}
+
+func (r *Rotation) regularShiftAt(at time.Time) Shift {
+	length := r.ShiftLength.Duration
+	elapsed := at.Sub(r.Handover)
+	number := int64(elapsed / length)
+	if elapsed < 0 && elapsed%length != 0 {
+		number--
+	}
+	memberIdx := int(number % int64(len(r.Members)))
+	if memberIdx < 0 {
+		memberIdx += len(r.Members)
+	}
+	start := r.Handover.Add(time.Duration(number) * length)
+	return Shift{
+		Mail:  r.Members[memberIdx],
+		Start: start,
+		End:   start.Add(length),
+	}
+}
+
+// OnCall returns current shift and the one after it.
+func (r *Rotation) OnCall(now time.Time) (current Shift, next Shift) {
+	current = r.ShiftAt(now)
+	next = r.ShiftAt(current.End)
+	return current, next
+}
+
+func (rm *RotationManager) Get(name string) (Rotation, bool) {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+	rotation, ok := rm.rotations[strings.ToLower(name)]
+	if !ok {
+		return Rotation{}, false
+	}
+	return rotation.clone(), true
+}
+
+// List returns all rotations sorted by name.
+func (rm *RotationManager) List() []Rotation {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+	list := []Rotation{}
+	for _, rotation := range rm.rotations {
+		list = append(list, rotation.clone())
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// Set creates or replaces rotation.
+func (rm *RotationManager) Set(rotation Rotation) error {
+	err := rotation.Validate()
+	if err != nil {
+		return err
+	}
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	previous, existed := rm.rotations[rotation.Name]
+	stored := rotation.clone()
+	rm.rotations[rotation.Name] = &stored
+	err = rm.save()
+	if err != nil {
+		// Roll back
+		if existed {
+			rm.rotations[rotation.Name] = previous
+		} else {
+			delete(rm.rotations, rotation.Name)
+		}
+		return err
+	}
+	return nil
+}
+
+func (rm *RotationManager) Delete(name string) error {
+	name = strings.ToLower(name)
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	previous, ok := rm.rotations[name]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(rm.rotations, name)
+	err := rm.save()
+	if err != nil {
+		rm.rotations[name] = previous
+		return err
+	}
+	return nil
+}
+
+// CurrentMail returns mail of member who is on call now.
+func (rm *RotationManager) CurrentMail(name string) (string, error) {
+	rotation, ok := rm.Get(name)
+	if !ok {
+		return "", ErrNotFound
+	}
+	return rotation.ShiftAt(time.Now()).Mail, nil
+}
+
+func (rm *RotationManager) save() error {
+	return filestore.SaveJSON(rm.file, rm.rotations)
+}
+
+func (r *Rotation) clone() Rotation {
+	clone := *r
+	clone.Members = slices.Clone(r.Members)
+	clone.Overrides = slices.Clone(r.Overrides)
+	return clone
+}
@@ -3,13 +3,27 @@ package main
 import (
 	"errors"
 	"fmt"
-	"io/fs"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sendyxmail/ackmanager"
+	"sendyxmail/aliasmanager"
 	"sendyxmail/apiv0"
+	"sendyxmail/chataliasmanager"
+	"sendyxmail/chatregistry"
+	"sendyxmail/digestmanager"
+	"sendyxmail/escalationmanager"
 	"sendyxmail/mutemanager"
+	"sendyxmail/queuemanager"
+	"sendyxmail/quietmanager"
+	"sendyxmail/rotationmanager"
+	"sendyxmail/syslogrelay"
+	"sendyxmail/threadmanager"
 	"sendyxmail/tokenmanager"
+	"sendyxmail/topicmanager"
+	"sendyxmail/usercache"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -18,19 +32,51 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/google/uuid"
 )
 
-const groupChatMailSuffix = "@chat-id.internal"
+const (
+	groupChatMailSuffix = "@chat-id.internal"
+	rotationMailSuffix  = "@rotation.internal"
+	chatAliasMailSuffix = "@chat.internal"
+	// syslogSender is sender name of messages relayed from syslog
+	syslogSender = "syslog"
+)
 
 var (
-	tm *tokenmanager.TokenManager
-	mm *mutemanager.MuteManager
+	tm             *tokenmanager.TokenManager
+	mm             *mutemanager.MuteManager
+	am             *ackmanager.AckManager
+	rotm           *rotationmanager.RotationManager
+	cam            *chataliasmanager.ChatAliasManager
+	cr             *chatregistry.ChatRegistry
+	qm             *quietmanager.QuietManager
+	tpm            *topicmanager.TopicManager
+	dgm            *digestmanager.DigestManager
+	botHuid        uuid.UUID
+	metadataSecret string
+	apiConfig      apiv0.APIConfig
+	// apiReady is closed when apiConfig is assigned
+	apiReady = make(chan struct{})
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate-mutes" {
+		err := migrateMutes(os.Args[2:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	var err error
 	botCreds := getEnvVarOrPanic("BOT_CREDENTIALS", 71, "BOT_CREDENTIALS must be provided as env variable in format 'cts_server@bot_secret@bot_id'")
-	metadataSecret := getEnvVarOrPanic("METADATA_SECRET", 20, "METADATA_SECRET must be provided as env variable and must be at least 20 characters")
+	// Bot ID is HUID of the bot in system events
+	botHuid, err = uuid.Parse(botCreds[strings.LastIndex(botCreds, "@")+1:])
+	if err != nil {
+		panic("BOT_CREDENTIALS must end with bot_id in UUID format")
+	}
+	metadataSecret = getEnvVarOrPanic("METADATA_SECRET", 20, "METADATA_SECRET must be provided as env variable and must be at least 20 characters")
 	tokenFile := getEnvVarOrPanic("TOKEN_FILE", 2, "TOKEN_FILE path must be provided as env variable")
 	muteFile := getEnvVarOrPanic("MUTE_FILE", 2, "MUTE_FILE path must be provided as env variable")
 	port := "8000"
@@ -40,20 +86,113 @@ func main() {
 
 	isDebug := strings.HasPrefix(strings.ToLower(os.Getenv("DEBUG")), "true")
 
+	if welcomeEnabled, ok := os.LookupEnv("WELCOME_MESSAGE"); ok {
+		welcome.Enabled = !strings.HasPrefix(strings.ToLower(welcomeEnabled), "false")
+	}
+	if welcomeDocsURL, ok := os.LookupEnv("WELCOME_DOCS_URL"); ok {
+		welcome.DocsURL = strings.TrimSpace(welcomeDocsURL)
+	}
+
 	tm, err = tokenmanager.Run(tokenFile, time.Duration(10*time.Minute))
 	if err != nil {
 		panic(err)
 	}
 
-	mm, err = mutemanager.New(muteFile)
+	// Mutes are stored in MUTE_FILE unless database is set in MUTE_DSN
+	muteStorage, err := mutemanager.OpenStorage(os.Getenv("MUTE_DSN"), muteFile)
 	if err != nil {
-		if errors.Is(err, fs.ErrNotExist) {
-			os.WriteFile(muteFile, []byte{}, 0666)
-			mm, err = mutemanager.New(muteFile)
-			if err != nil {
-				panic(err)
-			}
-		} else {
+		panic(err)
+	}
+	mm, err = mutemanager.New(muteStorage)
+	if err != nil {
+		panic(err)
+	}
+
+	// Acks are stored next to mutes
+	ackFile := filepath.Join(filepath.Dir(muteFile), "acks.json")
+	am, err = ackmanager.Run(ackFile, 7*24*time.Hour, func(ack ackmanager.Ack) {
+		err := apiv0.DeliverAckReminder(readyAPIConfig(), ack)
+		if err != nil {
+			log.Printf("failed to escalate unacknowledged message %s: %s", ack.Id, err.Error())
+		}
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	rotm, err = rotationmanager.New(filepath.Join(filepath.Dir(muteFile), "rotations.json"))
+	if err != nil {
+		panic(err)
+	}
+
+	cam, err = chataliasmanager.New(filepath.Join(filepath.Dir(muteFile), "chat-aliases.json"))
+	if err != nil {
+		panic(err)
+	}
+
+	cr, err = chatregistry.New(filepath.Join(filepath.Dir(muteFile), "chats.json"))
+	if err != nil {
+		panic(err)
+	}
+
+	tpm, err = topicmanager.New(filepath.Join(filepath.Dir(muteFile), "topics.json"))
+	if err != nil {
+		panic(err)
+	}
+
+	dgm, err = digestmanager.Run(filepath.Join(filepath.Dir(muteFile), "digests.json"), func(chatId uuid.UUID, messages []digestmanager.Message) error {
+		return apiv0.DeliverDigest(readyAPIConfig(), chatId, messages)
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	qm, err = quietmanager.Run(filepath.Join(filepath.Dir(muteFile), "quiet.json"), func(chatId uuid.UUID, messages []quietmanager.HeldMessage) error {
+		return apiv0.DeliverQuietDigest(readyAPIConfig(), chatId, messages)
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// Messages sent with on_muted "queue" wait here until recipient is unmuted
+	mutedQueue, err := queuemanager.Run(filepath.Join(filepath.Dir(muteFile), "muted-queue.json"), mm.GetMute, func(entry string, message queuemanager.Message) error {
+		return apiv0.DeliverQueued(readyAPIConfig(), entry, message)
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	threads, err := threadmanager.Run(filepath.Join(filepath.Dir(muteFile), "threads.json"), 30*24*time.Hour)
+	if err != nil {
+		panic(err)
+	}
+
+	userCache, err := newUserCache(filepath.Join(filepath.Dir(muteFile), "user-cache.json"))
+	if err != nil {
+		panic(err)
+	}
+
+	var aliases *aliasmanager.AliasManager
+	if aliasFile, ok := os.LookupEnv("ALIAS_FILE"); ok {
+		aliases, err = aliasmanager.Run(aliasFile, groupChatMailSuffix, time.Duration(10*time.Minute))
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	var em *escalationmanager.EscalationManager
+	if escalationFile, ok := os.LookupEnv("ESCALATION_FILE"); ok {
+		em, err = escalationmanager.Run(escalationFile,
+			filepath.Join(filepath.Dir(muteFile), "escalations.json"),
+			30*24*time.Hour,
+			func(escalation escalationmanager.Escalation, to string) (string, error) {
+				return apiv0.EscalationStep(readyAPIConfig())(escalation, to)
+			},
+			func(ackId uuid.UUID) bool {
+				ack, ok := am.Get(ackId)
+				return ok && ack.Acknowledged()
+			})
+		if err != nil {
 			panic(err)
 		}
 	}
@@ -98,13 +237,41 @@ func main() {
 	apiGroup.Use(logger.New())
 	apiGroup.Use(recover.New())
 
-	apiGroup.Mount("/v0", apiv0.New(apiv0.APIConfig{
+	apiConfig = apiv0.APIConfig{
 		Bot:                      b,
 		GroupChatMailSuffix:      groupChatMailSuffix,
 		CheckBearerToken:         checkToken,
+		CheckAdminToken:          checkAdminToken,
 		MetadataEncryptionSecret: metadataSecret,
 		CheckAllowedSend:         checkAllowedSend,
-	}))
+		AckManager:               am,
+		AckFallbackAddress:       os.Getenv("ACK_FALLBACK_ADDRESS"),
+		EscalationManager:        em,
+		RotationManager:          rotm,
+		RotationMailSuffix:       rotationMailSuffix,
+		AliasManager:             aliases,
+		ChatAliasManager:         cam,
+		ChatAliasMailSuffix:      chatAliasMailSuffix,
+		ChatRegistry:             cr,
+		UserCache:                userCache,
+		ThreadManager:            threads,
+		QuietManager:             qm,
+		SenderName:               tm.SenderName,
+		CheckAllowedSender:       checkAllowedSender,
+		TopicManager:             tpm,
+		DigestManager:            dgm,
+		MuteManager:              mm,
+		QueueManager:             mutedQueue,
+	}
+	close(apiReady)
+	apiGroup.Mount("/v0", apiv0.New(apiConfig))
+
+	if syslogRulesFile, ok := os.LookupEnv("SYSLOG_RULES_FILE"); ok {
+		err = runSyslogRelay(syslogRulesFile, &apiConfig)
+		if err != nil {
+			panic(err)
+		}
+	}
 
 	go func() {
 		if err := app.Listen(":" + port); err != nil {
@@ -119,6 +286,81 @@ func main() {
 	_ = app.Shutdown()
 }
 
+// readyAPIConfig waits until apiConfig is assigned.
+// Managers started before it deliver messages in background through it.
+func readyAPIConfig() *apiv0.APIConfig {
+	<-apiReady
+	return &apiConfig
+}
+
+func runSyslogRelay(rulesFile string, apiConfig *apiv0.APIConfig) error {
+	relay, err := syslogrelay.Run(syslogrelay.Config{
+		RulesFile:       rulesFile,
+		RefreshInterval: time.Minute,
+		Send: func(to string, body string) error {
+			_, err := apiv0.Deliver(apiConfig, apiv0.Message{To: to, Body: body}, syslogSender, false)
+			return err
+		},
+	})
+	if err != nil {
+		return err
+	}
+	udpAddr, hasUdp := os.LookupEnv("SYSLOG_UDP_ADDR")
+	tcpAddr, hasTcp := os.LookupEnv("SYSLOG_TCP_ADDR")
+	if !hasUdp && !hasTcp {
+		udpAddr, hasUdp = ":5514", true
+	}
+	if hasUdp {
+		err = relay.ListenUDP(udpAddr)
+		if err != nil {
+			return err
+		}
+	}
+	if hasTcp {
+		err = relay.ListenTCP(tcpAddr)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newUserCache creates user lookup cache configured by USER_CACHE_* env variables.
+// Cache is disabled when USER_CACHE_SIZE is 0.
+func newUserCache(file string) (*usercache.UserCache, error) {
+	config := usercache.Config{
+		Capacity:    10000,
+		TTL:         time.Hour,
+		NegativeTTL: 5 * time.Minute,
+	}
+	if size, ok := os.LookupEnv("USER_CACHE_SIZE"); ok {
+		capacity, err := strconv.Atoi(size)
+		if err != nil {
+			return nil, fmt.Errorf("USER_CACHE_SIZE must be a number: %w", err)
+		}
+		if capacity <= 0 {
+			return nil, nil
+		}
+		config.Capacity = capacity
+	}
+	for name, value := range map[string]*time.Duration{
+		"USER_CACHE_TTL":          &config.TTL,
+		"USER_CACHE_NEGATIVE_TTL": &config.NegativeTTL,
+	} {
+		if envValue, ok := os.LookupEnv(name); ok {
+			duration, err := time.ParseDuration(envValue)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be a duration like 30m: %w", name, err)
+			}
+			*value = duration
+		}
+	}
+	if strings.HasPrefix(strings.ToLower(os.Getenv("USER_CACHE_PERSIST")), "true") {
+		config.File = file
+	}
+	return usercache.New(config)
+}
+
 func checkToken(token string) error {
 	if tm.HasToken(token) {
 		return nil
@@ -126,6 +368,13 @@ func checkToken(token string) error {
 	return errors.New("token not registered in token manager")
 }
 
+func checkAdminToken(token string) error {
+	if tm.IsAdmin(token) {
+		return nil
+	}
+	return errors.New("token is not admin token")
+}
+
 func getEnvVarOrPanic(name string, minLen int, panicMessage string) string {
 	value, ok := os.LookupEnv(name)
 	value = strings.TrimSpace(value)
@@ -135,10 +384,17 @@ func getEnvVarOrPanic(name string, minLen int, panicMessage string) string {
 	return value
 }
 
-func checkAllowedSend(ident string) (err error) {
+func checkAllowedSender(chatId uuid.UUID, sender string) error {
+	entry := mutemanager.SenderEntry(chatId.String(), sender)
+	if until, muted := mm.MutedUntil(entry); muted {
+		return &mutemanager.MutedError{Entry: entry, Until: until, Sender: sender}
+	}
+	return nil
+}
 
-	if mm.GetMute(ident) {
-		return errors.New("bot is muted in this chat")
+func checkAllowedSend(ident string) (err error) {
+	if until, muted := mm.MutedUntil(ident); muted {
+		return &mutemanager.MutedError{Entry: ident, Until: until}
 	}
 	return nil
 }
@@ -12,6 +12,14 @@ func NewStatusHandler() botx.StatusCallbackHandler {
 		}
 		return models.NewStatusResponse(true, "",
 			commandMute,
-			commandUnmute)
+			commandUnmute,
+			commandStatus,
+			commandSenders,
+			commandSubscribe,
+			commandUnsubscribe,
+			commandQuiet,
+			commandDigest,
+			commandOnCall,
+			commandAlias)
 	}
 }
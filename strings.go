@@ -4,21 +4,169 @@ import "strings"
 
 var (
 	localizedMessages = map[string]string{
-		"ru-not_admin":           "Только админы чата могут управлять мной.",
-		"ru-muted":               "Я не буду доставлять сообщения в этот чат.",
-		"ru-unmuted":             "Я буду доставлять сообщения в этот чат.",
-		"ru-not_changed_muted":   "Я уже отключен.",
-		"ru-not_changed_unmuted": "Я доставлю сообщения в этот чат как только их кто-то отправит.",
-		"ru-show_chat_addr":      "Адрес данного чата для отправки сообщений через бота: `%s`",
-		"ru-error":               "Что-то пошло не так...",
+		"ru-not_admin":                  "Только админы чата могут управлять мной.",
+		"ru-muted":                      "Я не буду доставлять сообщения в этот чат.",
+		"ru-unmuted":                    "Я буду доставлять сообщения в этот чат.",
+		"ru-not_changed_muted":          "Я уже отключен.",
+		"ru-not_changed_unmuted":        "Я доставлю сообщения в этот чат как только их кто-то отправит.",
+		"ru-muted_until":                "Я не буду доставлять сообщения в этот чат до %s.",
+		"ru-mute_usage":                 "Использование: `/mute`, `/mute 2h`, `/mute 3d`, `/mute until 2026-11-01`, `/mute until 2026-11-01 09:00`, `/mute vacation`. Причину можно указать в конце: `/mute 2w reason: отпуск`",
+		"ru-show_chat_addr":             "Адрес данного чата для отправки сообщений через бота: `%s`",
+		"ru-error":                      "Что-то пошло не так...",
+		"ru-acked_by":                   "✅ Подтвердил(а) %s в %s",
+		"ru-already_acked":              "Уже подтверждено: %s",
+		"ru-ack_not_found":              "Это сообщение больше не ожидает подтверждения.",
+		"ru-oncall":                     "Дежурство **%s** (`%s`)\nСейчас: %s до %s\nДалее: %s с %s",
+		"ru-rotation_not_found":         "Дежурства не найдены.",
+		"ru-show_chat_aliases":          "Читаемые адреса данного чата: %s",
+		"ru-alias_none":                 "У этого чата нет читаемых адресов. Назначить адрес: `/alias set <имя>`",
+		"ru-alias_usage":                "Использование: `/alias set <имя>`, `/alias move <имя>`, `/alias remove <имя>`",
+		"ru-alias_invalid":              "Имя адреса может содержать от 2 до 63 строчных латинских букв, цифр, `.`, `_` и `-`.",
+		"ru-alias_set":                  "Теперь этот чат доступен по адресу `%s`",
+		"ru-alias_exists":               "Адрес `%s` уже назначен этому чату.",
+		"ru-alias_taken":                "Адрес `%s` уже занят другим чатом. Его владелец может перенести адрес сюда командой `/alias move`.",
+		"ru-alias_moved":                "Адрес `%s` перенесён в этот чат.",
+		"ru-alias_not_owner":            "Перенести адрес может только тот, кто его назначил.",
+		"ru-alias_not_found":            "Адрес `%s` не найден у этого чата.",
+		"ru-alias_removed":              "Адрес `%s` удалён.",
+		"ru-welcome":                    "Привет! Я доставляю в этот чат уведомления от систем мониторинга и других интеграций.\n\nАдрес этого чата для отправки сообщений через API: `%s`\n\nАдминистраторы чата могут управлять мной, упомянув меня перед командой:\n%s",
+		"ru-welcome_docs":               "Документация API: %s",
+		"ru-sender_usage":               "Использование: `/mute sender <имя> [2h|until 2026-11-01|vacation]`, `/unmute sender <имя>`. Список отправителей: `/senders`",
+		"ru-sender_unknown":             "Отправитель `%s` не найден. Список отправителей: `/senders`",
+		"ru-sender_muted":               "Я не буду доставлять в этот чат сообщения от `%s`.",
+		"ru-sender_muted_until":         "Я не буду доставлять в этот чат сообщения от `%s` до %s.",
+		"ru-sender_unmuted":             "Я снова доставляю в этот чат сообщения от `%s`.",
+		"ru-sender_not_changed_muted":   "Сообщения от `%s` уже не доставляются в этот чат.",
+		"ru-sender_not_changed_unmuted": "Сообщения от `%s` и так доставляются в этот чат.",
+		"ru-sender_mute_usage":          "Использование: `/mute sender <имя> [2h|until 2026-11-01|vacation]`",
+		"ru-sender_last_seen":           "последнее сообщение %s",
+		"ru-sender_muted_till":          "до %s",
+		"ru-senders_none":               "В этот чат ещё никто не присылал сообщения.",
+		"ru-senders_list":               "Отправители сообщений в этот чат:\n%s\n\nОтключить отправителя: `/mute sender <имя>`",
+		"ru-status_unmuted":             "🔔 Я доставляю сообщения в этот чат.",
+		"ru-status_muted":               "🔕 Я не доставляю сообщения в этот чат.",
+		"ru-status_muted_until":         "🔕 Я не доставляю сообщения в этот чат до %s.",
+		"ru-status_changed_by":          "Изменил(а): %s",
+		"ru-status_senders":             "Отключённые отправители:",
+		"ru-status_history":             "Последние изменения:",
+		"ru-mute_event_muted":           "отключено",
+		"ru-mute_event_muted_until":     "отключено до %s",
+		"ru-mute_event_unmuted":         "включено",
+		"ru-mute_origin":                "%s, %s",
+		"ru-mute_reason":                "причина: %s",
+		"ru-mute_source/chat":           "админ чата",
+		"ru-mute_source/api":            "администратор через API",
+		"ru-mute_source/system":         "бот",
+		"ru-command/status":             "показать, доставляются ли сообщения в этот чат, и кто и почему их отключил",
+		"ru-command/senders":            "показать, какие интеграции присылали сообщения в этот чат",
+		"ru-topics_none":                "Этот чат не подписан на темы. Подписаться: `/subscribe deployments.*`",
+		"ru-topics_list":                "Этот чат подписан на темы: %s\nОтписаться: `/unsubscribe <тема>`",
+		"ru-topic_invalid":              "Тема состоит из частей через точку, например `deployments.prod`. Части могут содержать строчные латинские буквы, цифры, `_` и `-`. `*` заменяет одну часть, `**` - любое количество частей.",
+		"ru-topic_subscribed":           "Этот чат подписан на `%s`.",
+		"ru-topic_already_subscribed":   "Этот чат уже подписан на `%s`.",
+		"ru-topic_unsubscribed":         "Этот чат отписан от `%s`.",
+		"ru-topic_not_subscribed":       "Этот чат не подписан на `%s`.",
+		"ru-command/subscribe":          "подписать этот чат на тему, например `/subscribe deployments.*`",
+		"ru-command/unsubscribe":        "отписать этот чат от темы",
+		"ru-digest_none":                "Режим сводки выключен. Включить: `/digest 15m`",
+		"ru-digest_show":                "Я присылаю сообщения в этот чат сводкой раз в %s. Выключить: `/digest off`",
+		"ru-digest_set":                 "Теперь я присылаю сообщения в этот чат сводкой раз в %s. Сообщения с `priority: high` доставляются сразу.",
+		"ru-digest_off":                 "Режим сводки выключен. Накопленные сообщения скоро придут.",
+		"ru-digest_usage":               "Использование: `/digest 15m`, `/digest 1h`, `/digest off`. Интервал - от 1m до 24h.",
+		"ru-command/digest":             "присылать сообщения сводкой раз в интервал, например `/digest 15m`",
+		"ru-quiet_none":                 "Тихие часы не настроены. Пример: `/quiet 22:00-08:00 Europe/Moscow weekdays`",
+		"ru-quiet_show":                 "Тихие часы: `%s`\nСообщения за это время я пришлю одним сообщением после их окончания. Выключить: `/quiet off`",
+		"ru-quiet_set":                  "Тихие часы установлены: `%s`\nСообщения за это время я пришлю одним сообщением после их окончания. Сообщения с `priority: high` доставляются сразу.",
+		"ru-quiet_off":                  "Тихие часы выключены.",
+		"ru-quiet_usage":                "Не понял расписание: %s\nИспользование: `/quiet 22:00-08:00 [часовой пояс] [daily|weekdays|weekends|mon,tue,...]`, `/quiet off`",
+		"ru-command/quiet":              "задержать уведомления на тихие часы, например `/quiet 22:00-08:00 Europe/Moscow weekdays`",
+		"ru-command/mute":               "перестать доставлять сообщения в этот чат, например `/mute 2h`, `/mute until 2026-11-01` или `/mute vacation`",
+		"ru-command/unmute":             "снова доставлять сообщения в этот чат",
+		"ru-command/oncall":             "показать, кто дежурит сейчас и следующим",
+		"ru-command/alias":              "управлять читаемым адресом этого чата",
+		"ru-command/_address":           "показать адрес этого чата",
 
-		"en-not_admin":           "Only chat admins can control me.",
-		"en-muted":               "I stopped delivering messages to this chat.",
-		"en-unmuted":             "I started delivering messages to this chat.",
-		"en-not_changed_muted":   "I already stopped delivering messages to this chat.",
-		"en-not_changed_unmuted": "I will deliver messages to this chat as soon as someone sends them.",
-		"en-show_chat_addr":      "The address of this chat for sending messages via the bot: `%s`",
-		"en-error":               "Something is wrong...",
+		"en-not_admin":                  "Only chat admins can control me.",
+		"en-muted":                      "I stopped delivering messages to this chat.",
+		"en-unmuted":                    "I started delivering messages to this chat.",
+		"en-not_changed_muted":          "I already stopped delivering messages to this chat.",
+		"en-not_changed_unmuted":        "I will deliver messages to this chat as soon as someone sends them.",
+		"en-muted_until":                "I stopped delivering messages to this chat until %s.",
+		"en-mute_usage":                 "Usage: `/mute`, `/mute 2h`, `/mute 3d`, `/mute until 2026-11-01`, `/mute until 2026-11-01 09:00`, `/mute vacation`. Reason can be given at the end: `/mute 2w reason: on vacation`",
+		"en-show_chat_addr":             "The address of this chat for sending messages via the bot: `%s`",
+		"en-error":                      "Something is wrong...",
+		"en-acked_by":                   "✅ Acknowledged by %s at %s",
+		"en-already_acked":              "Already acknowledged: %s",
+		"en-ack_not_found":              "This message no longer waits for acknowledgement.",
+		"en-oncall":                     "Rotation **%s** (`%s`)\nNow: %s until %s\nNext: %s from %s",
+		"en-rotation_not_found":         "No rotations found.",
+		"en-show_chat_aliases":          "Readable addresses of this chat: %s",
+		"en-alias_none":                 "This chat has no readable addresses. Set one with `/alias set <name>`",
+		"en-alias_usage":                "Usage: `/alias set <name>`, `/alias move <name>`, `/alias remove <name>`",
+		"en-alias_invalid":              "Address name may contain 2 to 63 lowercase latin letters, digits, `.`, `_` and `-`.",
+		"en-alias_set":                  "This chat is now available at `%s`",
+		"en-alias_exists":               "Address `%s` is already set for this chat.",
+		"en-alias_taken":                "Address `%s` is used by another chat. Its owner can move it here with `/alias move`.",
+		"en-alias_moved":                "Address `%s` is moved to this chat.",
+		"en-alias_not_owner":            "Only the user who set the address can move it.",
+		"en-alias_not_found":            "Address `%s` is not found for this chat.",
+		"en-alias_removed":              "Address `%s` is removed.",
+		"en-welcome":                    "Hi! I deliver notifications from monitoring and other integrations to this chat.\n\nThe address of this chat for sending messages via the API: `%s`\n\nChat admins can control me by mentioning me before a command:\n%s",
+		"en-welcome_docs":               "API documentation: %s",
+		"en-sender_usage":               "Usage: `/mute sender <name> [2h|until 2026-11-01|vacation]`, `/unmute sender <name>`. List senders: `/senders`",
+		"en-sender_unknown":             "Sender `%s` is not found. List senders: `/senders`",
+		"en-sender_muted":               "I stopped delivering messages from `%s` to this chat.",
+		"en-sender_muted_until":         "I stopped delivering messages from `%s` to this chat until %s.",
+		"en-sender_unmuted":             "I started delivering messages from `%s` to this chat again.",
+		"en-sender_not_changed_muted":   "Messages from `%s` are already not delivered to this chat.",
+		"en-sender_not_changed_unmuted": "Messages from `%s` are already delivered to this chat.",
+		"en-sender_mute_usage":          "Usage: `/mute sender <name> [2h|until 2026-11-01|vacation]`",
+		"en-sender_last_seen":           "last message %s",
+		"en-sender_muted_till":          "until %s",
+		"en-senders_none":               "Nobody has sent messages to this chat yet.",
+		"en-senders_list":               "Senders of messages to this chat:\n%s\n\nMute sender: `/mute sender <name>`",
+		"en-status_unmuted":             "🔔 I deliver messages to this chat.",
+		"en-status_muted":               "🔕 I do not deliver messages to this chat.",
+		"en-status_muted_until":         "🔕 I do not deliver messages to this chat until %s.",
+		"en-status_changed_by":          "Changed by %s",
+		"en-status_senders":             "Muted senders:",
+		"en-status_history":             "Latest changes:",
+		"en-mute_event_muted":           "muted",
+		"en-mute_event_muted_until":     "muted until %s",
+		"en-mute_event_unmuted":         "unmuted",
+		"en-mute_origin":                "%s at %s",
+		"en-mute_reason":                "reason: %s",
+		"en-mute_source/chat":           "chat admin",
+		"en-mute_source/api":            "administrator via API",
+		"en-mute_source/system":         "bot",
+		"en-command/status":             "show whether messages are delivered to this chat, and who muted them and why",
+		"en-command/senders":            "show integrations that posted to this chat",
+		"en-topics_none":                "This chat is not subscribed to topics. Subscribe: `/subscribe deployments.*`",
+		"en-topics_list":                "This chat is subscribed to topics: %s\nUnsubscribe: `/unsubscribe <topic>`",
+		"en-topic_invalid":              "Topic consists of parts separated by dots, for example `deployments.prod`. Parts may contain lowercase latin letters, digits, `_` and `-`. `*` matches one part, `**` matches any number of parts.",
+		"en-topic_subscribed":           "This chat is subscribed to `%s`.",
+		"en-topic_already_subscribed":   "This chat is already subscribed to `%s`.",
+		"en-topic_unsubscribed":         "This chat is unsubscribed from `%s`.",
+		"en-topic_not_subscribed":       "This chat is not subscribed to `%s`.",
+		"en-command/subscribe":          "subscribe this chat to topic, for example `/subscribe deployments.*`",
+		"en-command/unsubscribe":        "unsubscribe this chat from topic",
+		"en-digest_none":                "Digest mode is off. Turn on: `/digest 15m`",
+		"en-digest_show":                "I send messages to this chat as a digest every %s. Turn off: `/digest off`",
+		"en-digest_set":                 "Now I send messages to this chat as a digest every %s. Messages with `priority: high` are delivered immediately.",
+		"en-digest_off":                 "Digest mode is off. Collected messages will arrive soon.",
+		"en-digest_usage":               "Usage: `/digest 15m`, `/digest 1h`, `/digest off`. Interval is from 1m to 24h.",
+		"en-command/digest":             "send messages as a digest every interval, for example `/digest 15m`",
+		"en-quiet_none":                 "Quiet hours are not set. Example: `/quiet 22:00-08:00 Europe/Moscow weekdays`",
+		"en-quiet_show":                 "Quiet hours: `%s`\nMessages received during them will be sent as one message when they end. Turn off: `/quiet off`",
+		"en-quiet_set":                  "Quiet hours are set: `%s`\nMessages received during them will be sent as one message when they end. Messages with `priority: high` are delivered immediately.",
+		"en-quiet_off":                  "Quiet hours are turned off.",
+		"en-quiet_usage":                "Unable to understand schedule: %s\nUsage: `/quiet 22:00-08:00 [time zone] [daily|weekdays|weekends|mon,tue,...]`, `/quiet off`",
+		"en-command/quiet":              "hold notifications during quiet hours, for example `/quiet 22:00-08:00 Europe/Moscow weekdays`",
+		"en-command/mute":               "stop delivering messages to this chat, for example `/mute 2h`, `/mute until 2026-11-01` or `/mute vacation`",
+		"en-command/unmute":             "start delivering messages to this chat again",
+		"en-command/oncall":             "show who is on call now and next",
+		"en-command/alias":              "manage readable address of this chat",
+		"en-command/_address":           "show the address of this chat",
 	}
 )
 
@@ -0,0 +1,110 @@
+package syslogrelay
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const maxMessageSize = 64 * 1024
+
+// ListenUDP receives one syslog message per datagram.
+func (r *Relay) ListenUDP(addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		buf := make([]byte, maxMessageSize)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				log.Printf("syslog udp listener stopped: %s\n", err.Error())
+				return
+			}
+			r.handleLine(string(buf[:n]))
+		}
+	}()
+	return nil
+}
+
+// ListenTCP receives syslog messages framed with octet counting (RFC 6587)
+// or separated by newlines.
+func (r *Relay) ListenTCP(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				log.Printf("syslog tcp listener stopped: %s\n", err.Error())
+				return
+			}
+			go r.serveTCP(conn)
+		}
+	}()
+	return nil
+}
+
+func (r *Relay) serveTCP(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReaderSize(conn, maxMessageSize)
+	for {
+		first, err := reader.Peek(1)
+		if err != nil {
+			return
+		}
+		var line string
+		if first[0] >= '0' && first[0] <= '9' {
+			line, err = readOctetCounted(reader)
+		} else {
+			line, err = reader.ReadString('\n')
+			if errors.Is(err, io.EOF) && line != "" {
+				err = nil
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Printf("syslog tcp connection from %s closed: %s\n", conn.RemoteAddr(), err.Error())
+			}
+			return
+		}
+		r.handleLine(line)
+	}
+}
+
+func readOctetCounted(reader *bufio.Reader) (string, error) {
+	lengthString, err := reader.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	length, err := strconv.Atoi(strings.TrimSuffix(lengthString, " "))
+	if err != nil || length <= 0 || length > maxMessageSize {
+		return "", errors.New("invalid octet counting frame")
+	}
+	buf := make([]byte, length)
+	_, err = io.ReadFull(reader, buf)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func (r *Relay) handleLine(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	msg, err := Parse(line)
+	if err != nil {
+		log.Printf("skipping syslog message: %s\n", err.Error())
+		return
+	}
+	r.Handle(msg)
+}
@@ -0,0 +1,187 @@
+package syslogrelay
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is syslog message parsed from RFC 5424 or RFC 3164 line.
+type Message struct {
+	Facility  int
+	Severity  int
+	Timestamp time.Time
+	Hostname  string
+	AppName   string
+	Text      string
+}
+
+var facilityNames = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+var severityNames = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+// FacilityName returns keyword for facility code.
+func FacilityName(facility int) string {
+	if facility < 0 || facility >= len(facilityNames) {
+		return strconv.Itoa(facility)
+	}
+	return facilityNames[facility]
+}
+
+// SeverityName returns keyword for severity code.
+func SeverityName(severity int) string {
+	if severity < 0 || severity >= len(severityNames) {
+		return strconv.Itoa(severity)
+	}
+	return severityNames[severity]
+}
+
+func parseFacility(value string) (int, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for idx, name := range facilityNames {
+		if name == value {
+			return idx, nil
+		}
+	}
+	code, err := strconv.Atoi(value)
+	if err != nil || code < 0 || code >= len(facilityNames) {
+		return 0, fmt.Errorf("unknown syslog facility '%s'", value)
+	}
+	return code, nil
+}
+
+func parseSeverity(value string) (int, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "emergency", "panic":
+		value = "emerg"
+	case "critical":
+		value = "crit"
+	case "error":
+		value = "err"
+	case "warn":
+		value = "warning"
+	case "informational":
+		value = "info"
+	}
+	for idx, name := range severityNames {
+		if name == value {
+			return idx, nil
+		}
+	}
+	code, err := strconv.Atoi(value)
+	if err != nil || code < 0 || code >= len(severityNames) {
+		return 0, fmt.Errorf("unknown syslog severity '%s'", value)
+	}
+	return code, nil
+}
+
+// Parse parses single syslog message.
+// RFC 5424 is detected by version "1" right after PRI part, otherwise RFC 3164 is assumed.
+func Parse(line string) (*Message, error) {
+	line = strings.TrimRight(line, "\r\n\x00")
+	if !strings.HasPrefix(line, "<") {
+		return nil, errors.New("syslog message must start with PRI part")
+	}
+	end := strings.IndexByte(line, '>')
+	if end < 2 || end > 4 {
+		return nil, errors.New("invalid PRI part")
+	}
+	pri, err := strconv.Atoi(line[1:end])
+	if err != nil || pri < 0 || pri > 191 {
+		return nil, errors.New("invalid PRI value")
+	}
+	msg := &Message{
+		Facility: pri / 8,
+		Severity: pri % 8,
+	}
+	rest := line[end+1:]
+	if strings.HasPrefix(rest, "1 ") {
+		parseRFC5424(msg, rest[2:])
+	} else {
+		parseRFC3164(msg, rest)
+	}
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+	return msg, nil
+}
+
+func parseRFC5424(msg *Message, rest string) {
+	// TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	fields := strings.SplitN(rest, " ", 6)
+	for len(fields) < 6 {
+		fields = append(fields, "")
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, fields[0]); err == nil {
+		msg.Timestamp = ts
+	}
+	msg.Hostname = nilValue(fields[1])
+	msg.AppName = nilValue(fields[2])
+	msg.Text = skipStructuredData(fields[5])
+	msg.Text = strings.TrimPrefix(msg.Text, "\ufeff")
+}
+
+func skipStructuredData(value string) string {
+	if strings.HasPrefix(value, "-") {
+		return strings.TrimPrefix(strings.TrimPrefix(value, "-"), " ")
+	}
+	inElement := false
+	escaped := false
+	for idx, r := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '[':
+			inElement = true
+		case r == ']':
+			inElement = false
+		case r == ' ' && !inElement:
+			return value[idx+1:]
+		}
+	}
+	return ""
+}
+
+func nilValue(value string) string {
+	if value == "-" {
+		return ""
+	}
+	return value
+}
+
+func parseRFC3164(msg *Message, rest string) {
+	// Mmm dd hh:mm:ss HOSTNAME TAG: MSG
+	if len(rest) >= 16 {
+		if ts, err := time.ParseInLocation(time.Stamp, rest[:15], time.Local); err == nil {
+			now := time.Now()
+			msg.Timestamp = ts.AddDate(now.Year(), 0, 0)
+			if msg.Timestamp.After(now.Add(24 * time.Hour)) {
+				msg.Timestamp = msg.Timestamp.AddDate(-1, 0, 0)
+			}
+			rest = rest[16:]
+			if host, tail, ok := strings.Cut(rest, " "); ok {
+				msg.Hostname = host
+				rest = tail
+			}
+		}
+	}
+	if tag, tail, ok := strings.Cut(rest, ": "); ok && !strings.Contains(tag, " ") {
+		if idx := strings.IndexByte(tag, '['); idx > 0 {
+			tag = tag[:idx]
+		}
+		msg.AppName = tag
+		rest = tail
+	}
+	msg.Text = rest
+}
@@ -0,0 +1,167 @@
+package syslogrelay
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SendFunc delivers message body to recipient address.
+type SendFunc func(to string, body string) error
+
+type Config struct {
+	// RulesFile is YAML file with list of rules. It is re-read when changed.
+	RulesFile       string
+	RefreshInterval time.Duration
+	// BatchInterval is how long matched lines are collected before sending.
+	BatchInterval time.Duration
+	// MaxBatchLines limits number of lines in one message. Extra lines are dropped and counted.
+	MaxBatchLines int
+	// RateLimit is maximum number of messages sent to one recipient during RateInterval.
+	RateLimit    int
+	RateInterval time.Duration
+	Send         SendFunc
+}
+
+type Relay struct {
+	config  Config
+	rules   *ruleSet
+	batches map[string]*batch
+	mutex   sync.Mutex
+}
+
+type batch struct {
+	ruleNames []string
+	lines     []string
+	dropped   int
+	sentAt    []time.Time
+}
+
+func Run(config Config) (*Relay, error) {
+	if config.Send == nil {
+		return nil, fmt.Errorf("syslog relay requires send function")
+	}
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = time.Minute
+	}
+	if config.BatchInterval <= 0 {
+		config.BatchInterval = 10 * time.Second
+	}
+	if config.MaxBatchLines <= 0 {
+		config.MaxBatchLines = 50
+	}
+	if config.RateLimit <= 0 {
+		config.RateLimit = 10
+	}
+	if config.RateInterval <= 0 {
+		config.RateInterval = time.Minute
+	}
+	rules, err := loadRuleSet(config.RulesFile, config.RefreshInterval)
+	if err != nil {
+		return nil, err
+	}
+	r := &Relay{
+		config:  config,
+		rules:   rules,
+		batches: map[string]*batch{},
+	}
+	go func() {
+		for {
+			time.Sleep(r.config.BatchInterval)
+			r.flush()
+		}
+	}()
+	return r, nil
+}
+
+// Handle queues message for every recipient whose rule matches it.
+func (r *Relay) Handle(msg *Message) {
+	matched := r.rules.match(msg)
+	if len(matched) == 0 {
+		return
+	}
+	line := formatLine(msg)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, rule := range matched {
+		b, ok := r.batches[rule.To]
+		if !ok {
+			b = &batch{}
+			r.batches[rule.To] = b
+		}
+		if !slices.Contains(b.ruleNames, rule.Name) {
+			b.ruleNames = append(b.ruleNames, rule.Name)
+		}
+		if len(b.lines) >= r.config.MaxBatchLines {
+			b.dropped++
+			continue
+		}
+		b.lines = append(b.lines, line)
+	}
+}
+
+func (r *Relay) flush() {
+	type outgoing struct {
+		to   string
+		body string
+	}
+	toSend := []outgoing{}
+	now := time.Now()
+
+	r.mutex.Lock()
+	for to, b := range r.batches {
+		b.sentAt = slices.DeleteFunc(b.sentAt, func(t time.Time) bool {
+			return now.Sub(t) >= r.config.RateInterval
+		})
+		if len(b.lines) == 0 && b.dropped == 0 {
+			if len(b.sentAt) == 0 {
+				delete(r.batches, to)
+			}
+			continue
+		}
+		if len(b.sentAt) >= r.config.RateLimit {
+			// Keep collecting until rate limit allows sending again
+			continue
+		}
+		toSend = append(toSend, outgoing{to: to, body: b.format()})
+		b.sentAt = append(b.sentAt, now)
+		b.ruleNames = nil
+		b.lines = nil
+		b.dropped = 0
+	}
+	r.mutex.Unlock()
+
+	for _, msg := range toSend {
+		err := r.config.Send(msg.to, msg.body)
+		if err != nil {
+			log.Printf("failed to forward syslog messages to %s: %s\n", msg.to, err.Error())
+		}
+	}
+}
+
+func (b *batch) format() string {
+	sb := strings.Builder{}
+	sb.WriteString("**syslog: " + strings.Join(b.ruleNames, ", ") + "**\n")
+	for _, line := range b.lines {
+		sb.WriteString(line + "\n")
+	}
+	if b.dropped > 0 {
+		sb.WriteString(fmt.Sprintf("_%d more messages were dropped_\n", b.dropped))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func formatLine(msg *Message) string {
+	parts := []string{"`" + msg.Timestamp.Format(time.DateTime) + "`"}
+	if msg.Hostname != "" {
+		parts = append(parts, msg.Hostname)
+	}
+	if msg.AppName != "" {
+		parts = append(parts, msg.AppName)
+	}
+	parts = append(parts, fmt.Sprintf("[%s.%s]:", FacilityName(msg.Facility), SeverityName(msg.Severity)))
+	return strings.Join(append(parts, msg.Text), " ")
+}
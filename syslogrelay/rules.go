@@ -0,0 +1,167 @@
+package syslogrelay
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Rule describes which syslog messages are forwarded to recipient address.
+// Empty filter fields match everything.
+type Rule struct {
+	Name       string   `yaml:"name"`
+	To         string   `yaml:"to"`
+	Facilities []string `yaml:"facilities"`
+	Severity   string   `yaml:"severity"`
+	Hostname   string   `yaml:"hostname"`
+	Message    string   `yaml:"message"`
+}
+
+type compiledRule struct {
+	Rule
+	facilities  map[int]bool
+	maxSeverity int
+	hostname    *regexp.Regexp
+	message     *regexp.Regexp
+}
+
+func compileRule(rule Rule) (*compiledRule, error) {
+	if rule.To == "" {
+		return nil, fmt.Errorf("rule '%s' has empty 'to'", rule.Name)
+	}
+	cr := &compiledRule{
+		Rule:        rule,
+		facilities:  map[int]bool{},
+		maxSeverity: len(severityNames) - 1,
+	}
+	if cr.Name == "" {
+		cr.Name = rule.To
+	}
+	for _, facility := range rule.Facilities {
+		code, err := parseFacility(facility)
+		if err != nil {
+			return nil, fmt.Errorf("rule '%s': %w", cr.Name, err)
+		}
+		cr.facilities[code] = true
+	}
+	var err error
+	if rule.Severity != "" {
+		cr.maxSeverity, err = parseSeverity(rule.Severity)
+		if err != nil {
+			return nil, fmt.Errorf("rule '%s': %w", cr.Name, err)
+		}
+	}
+	if rule.Hostname != "" {
+		cr.hostname, err = regexp.Compile(rule.Hostname)
+		if err != nil {
+			return nil, fmt.Errorf("rule '%s': bad hostname regex: %w", cr.Name, err)
+		}
+	}
+	if rule.Message != "" {
+		cr.message, err = regexp.Compile(rule.Message)
+		if err != nil {
+			return nil, fmt.Errorf("rule '%s': bad message regex: %w", cr.Name, err)
+		}
+	}
+	return cr, nil
+}
+
+func (cr *compiledRule) match(msg *Message) bool {
+	if len(cr.facilities) > 0 && !cr.facilities[msg.Facility] {
+		return false
+	}
+	if msg.Severity > cr.maxSeverity {
+		return false
+	}
+	if cr.hostname != nil && !cr.hostname.MatchString(msg.Hostname) {
+		return false
+	}
+	if cr.message != nil && !cr.message.MatchString(msg.Text) {
+		return false
+	}
+	return true
+}
+
+type ruleSet struct {
+	file            string
+	refreshInterval time.Duration
+	modTime         time.Time
+	rules           []*compiledRule
+	mutex           sync.RWMutex
+}
+
+func loadRuleSet(file string, refreshInterval time.Duration) (*ruleSet, error) {
+	rs := &ruleSet{
+		file:            file,
+		refreshInterval: refreshInterval,
+	}
+	err := rs.reloadRules()
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			time.Sleep(rs.refreshInterval)
+			err := rs.reloadRules()
+			if err != nil {
+				log.Printf("failed reloading syslog rules: %s\n", err.Error())
+			}
+		}
+	}()
+	return rs, nil
+}
+
+func (rs *ruleSet) reloadRules() error {
+	info, err := os.Stat(rs.file)
+	if err != nil {
+		return err
+	}
+	rs.mutex.RLock()
+	unchanged := info.ModTime().Equal(rs.modTime)
+	rs.mutex.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	data, err := os.ReadFile(rs.file)
+	if err != nil {
+		return err
+	}
+	var newRules []Rule
+	err = yaml.Unmarshal(data, &newRules)
+	if err != nil {
+		return err
+	}
+	compiled := []*compiledRule{}
+	for _, rule := range newRules {
+		cr, err := compileRule(rule)
+		if err != nil {
+			return err
+		}
+		compiled = append(compiled, cr)
+	}
+
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	rs.rules = compiled
+	rs.modTime = info.ModTime()
+	log.Printf("updated %d syslog rules from %s\n", len(compiled), rs.file)
+	return nil
+}
+
+func (rs *ruleSet) match(msg *Message) []*compiledRule {
+	rs.mutex.RLock()
+	defer rs.mutex.RUnlock()
+	matched := []*compiledRule{}
+	for _, rule := range rs.rules {
+		if rule.match(msg) {
+			matched = append(matched, rule)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"sendyxmail/mutemanager"
+	"slices"
+
+	"github.com/go-botx/botx"
+	"github.com/go-botx/botx/models"
+)
+
+// BotX system events are delivered as commands of system type
+const (
+	systemEventChatCreated     = "system:chat_created"
+	systemEventAddedToChat     = "system:added_to_chat"
+	systemEventDeletedFromChat = "system:deleted_from_chat"
+	systemEventLeftFromChat    = "system:left_from_chat"
+)
+
+type systemEventData struct {
+	Name           string                  `json:"name"`
+	ChatType       string                  `json:"chat_type"`
+	Members        []systemEventChatMember `json:"members"`
+	AddedMembers   []string                `json:"added_members"`
+	DeletedMembers []string                `json:"deleted_members"`
+	LeftMembers    []string                `json:"left_members"`
+}
+
+type systemEventChatMember struct {
+	Huid string `json:"huid"`
+}
+
+// handleSystemEvent keeps chat registry up to date.
+// When the bot is removed from a chat, the chat is also removed from mute list.
+func handleSystemEvent(b *botx.Bot, req *models.CommandRequest) {
+	chatId := req.From.GroupChatId
+	raw, err := json.Marshal(req.Command.Data)
+	if err != nil {
+		return
+	}
+	var data systemEventData
+	err = json.Unmarshal(raw, &data)
+	if err != nil {
+		log.Printf("failed to decode %s event for chat %s: %s", req.Command.Body, chatId, err.Error())
+		return
+	}
+	chatType := data.ChatType
+	if chatType == "" {
+		chatType = string(req.From.ChatType)
+	}
+	botHuidString := botHuid.String()
+
+	isNew := false
+	switch req.Command.Body {
+	case systemEventChatCreated:
+		isNew, err = cr.Join(chatId, chatType, data.Name, len(data.Members))
+	case systemEventAddedToChat:
+		if slices.Contains(data.AddedMembers, botHuidString) {
+			isNew, err = cr.Join(chatId, chatType, data.Name, 0)
+		} else {
+			err = cr.ChangeMembers(chatId, len(data.AddedMembers))
+		}
+	case systemEventDeletedFromChat, systemEventLeftFromChat:
+		removed := append(data.DeletedMembers, data.LeftMembers...)
+		if slices.Contains(removed, botHuidString) {
+			err = cr.Leave(chatId)
+			if err == nil {
+				_, err = mm.SetMute(chatId.String(), false, mutemanager.Origin{
+					Source: mutemanager.SourceSystem,
+					Reason: "bot removed from chat",
+				})
+			}
+			if err == nil {
+				err = tpm.RemoveChat(chatId)
+			}
+		} else {
+			err = cr.ChangeMembers(chatId, -len(removed))
+		}
+	default:
+		return
+	}
+	if err != nil {
+		log.Printf("failed to handle %s event for chat %s: %s", req.Command.Body, chatId, err.Error())
+	}
+	if isNew {
+		sendWelcome(b, chatId, chatType, req.From.Locale)
+	}
+}
@@ -0,0 +1,126 @@
+package threadmanager
+
+import (
+	"log"
+	"sendyxmail/filestore"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Thread is a set of messages sent with the same key.
+// Follow-up messages with reply_to are sent as replies to them.
+type Thread struct {
+	Key       string        `json:"key"`
+	Messages  []SentMessage `json:"messages"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// SentMessage is the first message of thread in the chat.
+type SentMessage struct {
+	ChatId uuid.UUID `json:"chat_id"`
+	SyncId uuid.UUID `json:"sync_id"`
+}
+
+type ThreadManager struct {
+	file      string
+	retention time.Duration
+	threads   map[string]*Thread
+	mutex     sync.RWMutex
+}
+
+// Run loads threads from file.
+// Threads without new messages are forgotten after retention.
+func Run(file string, retention time.Duration) (*ThreadManager, error) {
+	tm := &ThreadManager{
+		file:      file,
+		retention: retention,
+		threads:   map[string]*Thread{},
+	}
+	err := filestore.LoadJSON(tm.file, &tm.threads)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			time.Sleep(time.Hour)
+			tm.cleanup()
+		}
+	}()
+	return tm, nil
+}
+
+// threadId makes keys of different tokens independent.
+func threadId(scope string, key string) string {
+	return scope + ":" + key
+}
+
+// Record remembers message sent with key.
+// Only the first message in every chat is remembered, so replies attach to the original notification.
+func (tm *ThreadManager) Record(scope string, key string, chatId uuid.UUID, syncId uuid.UUID) error {
+	id := threadId(scope, key)
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	previous, existed := tm.threads[id]
+	thread := &Thread{Key: key, Messages: []SentMessage{}}
+	if existed {
+		thread.Messages = slices.Clone(previous.Messages)
+	}
+	thread.UpdatedAt = time.Now().UTC()
+	if !slices.ContainsFunc(thread.Messages, func(m SentMessage) bool { return m.ChatId == chatId }) {
+		thread.Messages = append(thread.Messages, SentMessage{ChatId: chatId, SyncId: syncId})
+	}
+	tm.threads[id] = thread
+	err := tm.save()
+	if err != nil {
+		// Roll back
+		if existed {
+			tm.threads[id] = previous
+		} else {
+			delete(tm.threads, id)
+		}
+		return err
+	}
+	return nil
+}
+
+// Find returns sync_id of the message sent with key to the chat.
+func (tm *ThreadManager) Find(scope string, key string, chatId uuid.UUID) (uuid.UUID, bool) {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+	thread, ok := tm.threads[threadId(scope, key)]
+	if !ok {
+		return uuid.Nil, false
+	}
+	for _, message := range thread.Messages {
+		if message.ChatId == chatId {
+			return message.SyncId, true
+		}
+	}
+	return uuid.Nil, false
+}
+
+func (tm *ThreadManager) cleanup() {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	now := time.Now()
+	changed := false
+	for id, thread := range tm.threads {
+		if now.Sub(thread.UpdatedAt) > tm.retention {
+			delete(tm.threads, id)
+			changed = true
+		}
+	}
+	if changed {
+		err := tm.save()
+		if err != nil {
+			log.Printf("failed to save threads: %s", err.Error())
+		}
+	}
+}
+
+func (tm *ThreadManager) save() error {
+	return filestore.SaveJSON(tm.file, tm.threads)
+}
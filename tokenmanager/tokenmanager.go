@@ -1,10 +1,13 @@
 package tokenmanager
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log"
+	"maps"
 	"os"
-	"slices"
+	"regexp"
 	"sync"
 	"time"
 
@@ -15,17 +18,25 @@ type TokenManager struct {
 	refreshInterval time.Duration
 	file            string
 	tokens          map[string]tokenRecord
-	mutex           sync.RWMutex
+	// tokensById are the same tokens by TokenId of token
+	tokensById map[string]tokenRecord
+	mutex      sync.RWMutex
 }
 
 type tokenRecord struct {
-	Token string `yaml:"token"`
+	Token       string `yaml:"token"`
+	Name        string `yaml:"name"`
+	CallbackURL string `yaml:"callback_url"`
+	Admin       bool   `yaml:"admin"`
 }
 
+var senderNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,62}$`)
+
 func Run(tokenFile string, refreshInterval time.Duration) (*TokenManager, error) {
 	tm := &TokenManager{
 		file:            tokenFile,
 		tokens:          map[string]tokenRecord{},
+		tokensById:      map[string]tokenRecord{},
 		refreshInterval: refreshInterval,
 	}
 	err := tm.reloadTokens()
@@ -51,6 +62,55 @@ func (tm *TokenManager) HasToken(token string) bool {
 	return ok
 }
 
+// IsAdmin reports if token may use admin API.
+func (tm *TokenManager) IsAdmin(token string) bool {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+	record, ok := tm.tokens[token]
+	return ok && record.Admin
+}
+
+// TokenId returns hex encoded SHA-256 of token.
+// It is stored in button data and delayed messages instead of token itself.
+func TokenId(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// FindCallback returns token and its callback URL by TokenId of token.
+func (tm *TokenManager) FindCallback(tokenId string) (token string, callbackURL string, ok bool) {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+	record, ok := tm.tokensById[tokenId]
+	if !ok || record.CallbackURL == "" {
+		return "", "", false
+	}
+	return record.Token, record.CallbackURL, true
+}
+
+// SenderName returns name of token by TokenId of token.
+// Tokens without name are named by the beginning of TokenId.
+func (tm *TokenManager) SenderName(tokenId string) string {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+	if record, ok := tm.tokensById[tokenId]; ok && record.Name != "" {
+		return record.Name
+	}
+	return "token-" + tokenId[:min(len(tokenId), 12)]
+}
+
+// HasSender reports if token with given name is configured.
+func (tm *TokenManager) HasSender(name string) bool {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+	for _, record := range tm.tokens {
+		if record.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (tm *TokenManager) reloadTokens() error {
 	data, err := os.ReadFile(tm.file)
 	if err != nil {
@@ -62,12 +122,22 @@ func (tm *TokenManager) reloadTokens() error {
 		return err
 	}
 
-	newTokens := []string{}
+	newTokens := map[string]tokenRecord{}
+	names := map[string]bool{}
 	for idx, k := range newTokensRecords {
 		if k.Token == "" {
 			return fmt.Errorf("token number %d in file %s is empty", idx+1, tm.file)
 		}
-		newTokens = append(newTokens, k.Token)
+		if k.Name != "" {
+			if !senderNamePattern.MatchString(k.Name) {
+				return fmt.Errorf("name of token number %d in file %s may contain only lowercase latin letters, digits, '.', '_' and '-'", idx+1, tm.file)
+			}
+			if names[k.Name] {
+				return fmt.Errorf("name '%s' of token number %d in file %s is not unique", k.Name, idx+1, tm.file)
+			}
+			names[k.Name] = true
+		}
+		newTokens[k.Token] = k
 
 	}
 	if len(newTokens) < 1 {
@@ -75,18 +145,11 @@ func (tm *TokenManager) reloadTokens() error {
 	}
 
 	tm.mutex.RLock()
-	currentTokens := []string{}
-	for k, _ := range tm.tokens {
-		currentTokens = append(currentTokens, k)
-	}
+	unchanged := maps.Equal(newTokens, tm.tokens)
 	tm.mutex.RUnlock()
 
-	if len(newTokens) == len(currentTokens) {
-		slices.Sort(newTokens)
-		slices.Sort(currentTokens)
-		if slices.Equal(newTokens, currentTokens) {
-			return nil
-		}
+	if unchanged {
+		return nil
 	}
 
 	tm.mutex.Lock()
@@ -96,8 +159,10 @@ func (tm *TokenManager) reloadTokens() error {
 		delete(tm.tokens, k)
 	}
 
-	for _, tokenRecord := range newTokensRecords {
-		tm.tokens[tokenRecord.Token] = tokenRecord
+	maps.Copy(tm.tokens, newTokens)
+	tm.tokensById = map[string]tokenRecord{}
+	for token, record := range newTokens {
+		tm.tokensById[TokenId(token)] = record
 	}
 	log.Printf("updated tokens from %s\n", tm.file)
 	return nil
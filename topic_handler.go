@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sendyxmail/topicmanager"
+	"strings"
+
+	"github.com/go-botx/botx"
+	"github.com/go-botx/botx/models"
+)
+
+// handleTopics subscribes chat to topic pattern, unsubscribes it or lists subscriptions.
+func handleTopics(b *botx.Bot, req *models.CommandRequest, command string, args string) {
+	chatId := req.From.GroupChatId
+	locale := req.From.Locale
+	text := ""
+	if args == "" {
+		patterns := tpm.ForChat(chatId)
+		if len(patterns) == 0 {
+			text = getLocalizedMessage(locale, "topics_none")
+		} else {
+			text = fmt.Sprintf(getLocalizedMessage(locale, "topics_list"), "`"+strings.Join(patterns, "`, `")+"`")
+		}
+	} else {
+		var changed bool
+		var err error
+		subscribe := command == commandSubscribe.Body
+		if subscribe {
+			changed, err = tpm.Subscribe(chatId, args)
+		} else {
+			changed, err = tpm.Unsubscribe(chatId, args)
+		}
+		responseString := ""
+		switch {
+		case errors.Is(err, topicmanager.ErrInvalidPattern):
+			responseString = "topic_invalid"
+		case err != nil:
+			log.Printf("failed to change subscription of %s to '%s': %s", chatId, args, err.Error())
+			responseString = "error"
+		case subscribe && changed:
+			responseString = "topic_subscribed"
+		case subscribe:
+			responseString = "topic_already_subscribed"
+		case changed:
+			responseString = "topic_unsubscribed"
+		default:
+			responseString = "topic_not_subscribed"
+		}
+		text = getLocalizedMessage(locale, responseString)
+		if strings.HasPrefix(responseString, "topic_") && responseString != "topic_invalid" {
+			text = fmt.Sprintf(text, args)
+		}
+	}
+	message, err := models.NewNDRequest(chatId, text)
+	if err != nil {
+		return
+	}
+	b.SendMessageAsync(message)
+}
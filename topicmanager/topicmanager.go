@@ -0,0 +1,198 @@
+package topicmanager
+
+import (
+	"errors"
+	"regexp"
+	"sendyxmail/filestore"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Wildcards in subscription patterns
+const (
+	// AnySegment matches exactly one segment of topic
+	AnySegment = "*"
+	// AnySegments matches any number of segments of topic, including none
+	AnySegments = "**"
+)
+
+// MaxPatternSegments limits number of segments in subscription pattern.
+const MaxPatternSegments = 16
+
+var (
+	ErrInvalidTopic   = errors.New("invalid topic")
+	ErrInvalidPattern = errors.New("invalid topic pattern")
+
+	segmentPattern = regexp.MustCompile(`^[a-z0-9_-]{1,63}$`)
+)
+
+// TopicManager stores topic patterns every chat is subscribed to.
+type TopicManager struct {
+	file          string
+	subscriptions map[uuid.UUID][]string
+	mutex         sync.RWMutex
+}
+
+func New(file string) (*TopicManager, error) {
+	tpm := &TopicManager{
+		file:          file,
+		subscriptions: map[uuid.UUID][]string{},
+	}
+	err := filestore.LoadJSON(tpm.file, &tpm.subscriptions)
+	if err != nil {
+		return nil, err
+	}
+	return tpm, nil
+}
+
+// NormalizeTopic lowercases topic like deployments.prod and checks that it is valid.
+func NormalizeTopic(topic string) (string, error) {
+	topic = strings.ToLower(strings.TrimSpace(topic))
+	for _, segment := range strings.Split(topic, ".") {
+		if !segmentPattern.MatchString(segment) {
+			return "", ErrInvalidTopic
+		}
+	}
+	return topic, nil
+}
+
+// NormalizePattern lowercases pattern like deployments.* and checks that it is valid.
+// Consecutive ** are collapsed into one.
+func NormalizePattern(pattern string) (string, error) {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	segments := []string{}
+	for _, segment := range strings.Split(pattern, ".") {
+		if segment != AnySegment && segment != AnySegments && !segmentPattern.MatchString(segment) {
+			return "", ErrInvalidPattern
+		}
+		if segment == AnySegments && len(segments) > 0 && segments[len(segments)-1] == AnySegments {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+	if len(segments) > MaxPatternSegments {
+		return "", ErrInvalidPattern
+	}
+	return strings.Join(segments, "."), nil
+}
+
+// Match reports if normalized topic matches normalized pattern.
+func Match(pattern string, topic string) bool {
+	return matchSegments(strings.Split(pattern, "."), strings.Split(topic, "."))
+}
+
+// matchSegments matches pattern segment by segment.
+// matched[idx] reports if the pattern segments seen so far match the first idx segments of topic.
+func matchSegments(pattern []string, topic []string) bool {
+	matched := make([]bool, len(topic)+1)
+	matched[0] = true
+	for _, segment := range pattern {
+		next := make([]bool, len(topic)+1)
+		if segment == AnySegments {
+			seen := false
+			for idx := range matched {
+				seen = seen || matched[idx]
+				next[idx] = seen
+			}
+		} else {
+			for idx, topicSegment := range topic {
+				next[idx+1] = matched[idx] && (segment == AnySegment || segment == topicSegment)
+			}
+		}
+		matched = next
+	}
+	return matched[len(topic)]
+}
+
+// Subscribe adds pattern to subscriptions of chat.
+// changed is false if chat is already subscribed to the pattern.
+func (tpm *TopicManager) Subscribe(chatId uuid.UUID, pattern string) (changed bool, err error) {
+	pattern, err = NormalizePattern(pattern)
+	if err != nil {
+		return false, err
+	}
+	tpm.mutex.Lock()
+	defer tpm.mutex.Unlock()
+	previous := tpm.subscriptions[chatId]
+	if slices.Contains(previous, pattern) {
+		return false, nil
+	}
+	patterns := append(slices.Clone(previous), pattern)
+	slices.Sort(patterns)
+	tpm.subscriptions[chatId] = patterns
+	err = tpm.save()
+	if err != nil {
+		tpm.restore(chatId, previous)
+		return false, err
+	}
+	return true, nil
+}
+
+// Unsubscribe removes pattern from subscriptions of chat.
+// changed is false if chat is not subscribed to the pattern.
+func (tpm *TopicManager) Unsubscribe(chatId uuid.UUID, pattern string) (changed bool, err error) {
+	pattern, err = NormalizePattern(pattern)
+	if err != nil {
+		return false, err
+	}
+	tpm.mutex.Lock()
+	defer tpm.mutex.Unlock()
+	previous := tpm.subscriptions[chatId]
+	if !slices.Contains(previous, pattern) {
+		return false, nil
+	}
+	tpm.restore(chatId, slices.DeleteFunc(slices.Clone(previous), func(p string) bool { return p == pattern }))
+	err = tpm.save()
+	if err != nil {
+		tpm.restore(chatId, previous)
+		return false, err
+	}
+	return true, nil
+}
+
+// RemoveChat removes all subscriptions of chat, for example when the bot left it.
+func (tpm *TopicManager) RemoveChat(chatId uuid.UUID) error {
+	tpm.mutex.Lock()
+	defer tpm.mutex.Unlock()
+	if _, ok := tpm.subscriptions[chatId]; !ok {
+		return nil
+	}
+	delete(tpm.subscriptions, chatId)
+	return tpm.save()
+}
+
+// ForChat returns patterns chat is subscribed to.
+func (tpm *TopicManager) ForChat(chatId uuid.UUID) []string {
+	tpm.mutex.RLock()
+	defer tpm.mutex.RUnlock()
+	return slices.Clone(tpm.subscriptions[chatId])
+}
+
+// Subscribers returns chats subscribed to normalized topic.
+func (tpm *TopicManager) Subscribers(topic string) []uuid.UUID {
+	tpm.mutex.RLock()
+	defer tpm.mutex.RUnlock()
+	chats := []uuid.UUID{}
+	for chatId, patterns := range tpm.subscriptions {
+		if slices.ContainsFunc(patterns, func(pattern string) bool { return Match(pattern, topic) }) {
+			chats = append(chats, chatId)
+		}
+	}
+	slices.SortFunc(chats, func(a, b uuid.UUID) int { return strings.Compare(a.String(), b.String()) })
+	return chats
+}
+
+func (tpm *TopicManager) restore(chatId uuid.UUID, patterns []string) {
+	if len(patterns) == 0 {
+		delete(tpm.subscriptions, chatId)
+	} else {
+		tpm.subscriptions[chatId] = patterns
+	}
+}
+
+func (tpm *TopicManager) save() error {
+	return filestore.SaveJSON(tpm.file, tpm.subscriptions)
+}
@@ -0,0 +1,122 @@
+package topicmanager
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		topic   string
+		want    bool
+	}{
+		{"deployments.prod", "deployments.prod", true},
+		{"deployments.prod", "deployments.dev", false},
+		{"deployments.prod", "deployments", false},
+		{"deployments", "deployments.prod", false},
+		{"deployments.*", "deployments.prod", true},
+		{"deployments.*", "deployments", false},
+		{"deployments.*", "deployments.prod.db", false},
+		{"*.prod", "deployments.prod", true},
+		{"*.prod", "alerts.dev", false},
+		{"*", "deployments", true},
+		{"*", "deployments.prod", false},
+		{"deployments.**", "deployments", true},
+		{"deployments.**", "deployments.prod", true},
+		{"deployments.**", "deployments.prod.db", true},
+		{"deployments.**", "alerts.prod", false},
+		{"**", "deployments.prod.db", true},
+		{"**.db", "db", true},
+		{"**.db", "deployments.prod.db", true},
+		{"**.db", "deployments.db.prod", false},
+		{"deployments.**.db", "deployments.db", true},
+		{"deployments.**.db", "deployments.prod.eu.db", true},
+		{"deployments.**.db", "deployments.prod.eu", false},
+		{"**.prod.*", "deployments.prod.db", true},
+		{"**.prod.*", "deployments.prod", false},
+		{"*.**", "deployments", true},
+		{"*.*.**", "deployments", false},
+		{"**.**.**.**.**.**.**.**.**.**.**.**.**.**.x", strings.Repeat("a.", 24) + "b", false},
+		{"**.**.**.**.**.**.**.**.**.**.**.**.**.**.x", strings.Repeat("a.", 24) + "x", true},
+	}
+	for _, tt := range tests {
+		start := time.Now()
+		if got := Match(tt.pattern, tt.topic); got != tt.want {
+			t.Errorf("Match(%q, %q) = %t, want %t", tt.pattern, tt.topic, got, tt.want)
+		}
+		if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+			t.Errorf("Match(%q, %q) took %s", tt.pattern, tt.topic, elapsed)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	topics := []struct {
+		topic string
+		want  string
+		valid bool
+	}{
+		{" Deployments.Prod ", "deployments.prod", true},
+		{"deployments..prod", "", false},
+		{"deployments.*", "", false},
+		{"", "", false},
+	}
+	for _, tt := range topics {
+		got, err := NormalizeTopic(tt.topic)
+		if (err == nil) != tt.valid || got != tt.want {
+			t.Errorf("NormalizeTopic(%q) = %q, %v", tt.topic, got, err)
+		}
+	}
+	patterns := []struct {
+		pattern string
+		want    string
+		valid   bool
+	}{
+		{"Deployments.*", "deployments.*", true},
+		{"**.DB", "**.db", true},
+		{"deployments.***", "", false},
+		{"deployments.pr*d", "", false},
+		{"**.**.deployments.**.**.**", "**.deployments.**", true},
+		{strings.Repeat("a.", 16) + "b", "", false},
+	}
+	for _, tt := range patterns {
+		got, err := NormalizePattern(tt.pattern)
+		if (err == nil) != tt.valid || got != tt.want {
+			t.Errorf("NormalizePattern(%q) = %q, %v", tt.pattern, got, err)
+		}
+	}
+}
+
+func TestSubscribers(t *testing.T) {
+	tpm, err := New(filepath.Join(t.TempDir(), "topics.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prod := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	all := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	for chatId, pattern := range map[uuid.UUID]string{prod: "deployments.prod", all: "deployments.**"} {
+		if _, err := tpm.Subscribe(chatId, pattern); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := tpm.Subscribers("deployments.prod"); !slices.Equal(got, []uuid.UUID{prod, all}) {
+		t.Errorf("Subscribers(deployments.prod) = %v", got)
+	}
+	if got := tpm.Subscribers("deployments.dev"); !slices.Equal(got, []uuid.UUID{all}) {
+		t.Errorf("Subscribers(deployments.dev) = %v", got)
+	}
+
+	reloaded, err := New(tpm.file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.ForChat(all); !slices.Equal(got, []string{"deployments.**"}) {
+		t.Errorf("ForChat after reload = %v", got)
+	}
+}
@@ -0,0 +1,223 @@
+package usercache
+
+import (
+	"container/list"
+	"log"
+	"sendyxmail/filestore"
+	"sync"
+	"time"
+
+	"github.com/go-botx/botx/models"
+	"github.com/google/uuid"
+)
+
+// Entry is cached result of user lookup.
+// User is found user, ChatId is personal chat with the user if it was already needed.
+// NotFound entries are negative cache of lookups that found no users.
+type Entry struct {
+	Key       string           `json:"key"`
+	User      *models.UserInfo `json:"user,omitempty"`
+	ChatId    uuid.UUID        `json:"chat_id,omitempty"`
+	NotFound  bool             `json:"not_found,omitempty"`
+	ExpiresAt time.Time        `json:"expires_at"`
+}
+
+// Stats are counters of cache usage since start.
+type Stats struct {
+	Size         int    `json:"size"`
+	Capacity     int    `json:"capacity"`
+	Hits         uint64 `json:"hits"`
+	NegativeHits uint64 `json:"negative_hits"`
+	Misses       uint64 `json:"misses"`
+	Evictions    uint64 `json:"evictions"`
+}
+
+type Config struct {
+	Capacity    int
+	TTL         time.Duration
+	NegativeTTL time.Duration
+	// File is optional. When set, cache is loaded from it on start and saved every SaveInterval.
+	File         string
+	SaveInterval time.Duration
+}
+
+// UserCache is LRU cache mapping user identifier to user and personal chat.
+type UserCache struct {
+	config  Config
+	entries map[string]*list.Element
+	order   *list.List
+	stats   Stats
+	dirty   bool
+	mutex   sync.Mutex
+}
+
+func New(config Config) (*UserCache, error) {
+	uc := &UserCache{
+		config:  config,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+	uc.stats.Capacity = config.Capacity
+	if config.File == "" {
+		return uc, nil
+	}
+
+	stored := []Entry{}
+	err := filestore.LoadJSON(config.File, &stored)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	// Stored entries are ordered from the most recently used
+	for idx := len(stored) - 1; idx >= 0; idx-- {
+		if stored[idx].ExpiresAt.After(now) {
+			uc.put(stored[idx])
+		}
+	}
+	if config.SaveInterval <= 0 {
+		config.SaveInterval = time.Minute
+	}
+	go func() {
+		for {
+			time.Sleep(config.SaveInterval)
+			err := uc.save()
+			if err != nil {
+				log.Printf("failed to save user cache: %s", err.Error())
+			}
+		}
+	}()
+	return uc, nil
+}
+
+// Get returns not expired entry and counts hit or miss.
+func (uc *UserCache) Get(key string) (Entry, bool) {
+	uc.mutex.Lock()
+	defer uc.mutex.Unlock()
+	element, ok := uc.entries[key]
+	if ok {
+		entry := element.Value.(Entry)
+		if time.Now().Before(entry.ExpiresAt) {
+			uc.order.MoveToFront(element)
+			if entry.NotFound {
+				uc.stats.NegativeHits++
+			} else {
+				uc.stats.Hits++
+			}
+			return entry, true
+		}
+		uc.remove(element)
+	}
+	uc.stats.Misses++
+	return Entry{}, false
+}
+
+// Put caches user and personal chat with the user.
+func (uc *UserCache) Put(key string, user models.UserInfo, chatId uuid.UUID) {
+	uc.mutex.Lock()
+	defer uc.mutex.Unlock()
+	uc.put(Entry{
+		Key:       key,
+		User:      &user,
+		ChatId:    chatId,
+		ExpiresAt: time.Now().Add(uc.config.TTL),
+	})
+}
+
+// PutUser caches user when personal chat with the user is not needed yet.
+// Personal chat cached before is kept.
+func (uc *UserCache) PutUser(key string, user models.UserInfo) {
+	uc.mutex.Lock()
+	defer uc.mutex.Unlock()
+	entry := Entry{
+		Key:       key,
+		User:      &user,
+		ExpiresAt: time.Now().Add(uc.config.TTL),
+	}
+	if element, ok := uc.entries[key]; ok {
+		entry.ChatId = element.Value.(Entry).ChatId
+	}
+	uc.put(entry)
+}
+
+// PutNotFound caches that no users were found.
+func (uc *UserCache) PutNotFound(key string) {
+	if uc.config.NegativeTTL <= 0 {
+		return
+	}
+	uc.mutex.Lock()
+	defer uc.mutex.Unlock()
+	uc.put(Entry{
+		Key:       key,
+		NotFound:  true,
+		ExpiresAt: time.Now().Add(uc.config.NegativeTTL),
+	})
+}
+
+// Invalidate removes entry. It returns false if there was no such entry.
+func (uc *UserCache) Invalidate(key string) bool {
+	uc.mutex.Lock()
+	defer uc.mutex.Unlock()
+	element, ok := uc.entries[key]
+	if !ok {
+		return false
+	}
+	uc.remove(element)
+	return true
+}
+
+// Purge removes all entries and returns their number.
+func (uc *UserCache) Purge() int {
+	uc.mutex.Lock()
+	defer uc.mutex.Unlock()
+	count := len(uc.entries)
+	uc.entries = map[string]*list.Element{}
+	uc.order.Init()
+	uc.dirty = true
+	return count
+}
+
+func (uc *UserCache) Stats() Stats {
+	uc.mutex.Lock()
+	defer uc.mutex.Unlock()
+	stats := uc.stats
+	stats.Size = len(uc.entries)
+	return stats
+}
+
+func (uc *UserCache) put(entry Entry) {
+	if uc.config.Capacity <= 0 {
+		return
+	}
+	if element, ok := uc.entries[entry.Key]; ok {
+		element.Value = entry
+		uc.order.MoveToFront(element)
+	} else {
+		uc.entries[entry.Key] = uc.order.PushFront(entry)
+	}
+	for uc.order.Len() > uc.config.Capacity {
+		uc.remove(uc.order.Back())
+		uc.stats.Evictions++
+	}
+	uc.dirty = true
+}
+
+func (uc *UserCache) remove(element *list.Element) {
+	uc.order.Remove(element)
+	delete(uc.entries, element.Value.(Entry).Key)
+	uc.dirty = true
+}
+
+func (uc *UserCache) save() error {
+	uc.mutex.Lock()
+	if !uc.dirty {
+		uc.mutex.Unlock()
+		return nil
+	}
+	entries := make([]Entry, 0, uc.order.Len())
+	for element := uc.order.Front(); element != nil; element = element.Next() {
+		entries = append(entries, element.Value.(Entry))
+	}
+	uc.dirty = false
+	uc.mutex.Unlock()
+	return filestore.SaveJSON(uc.config.File, entries)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-botx/botx"
+	"github.com/go-botx/botx/models"
+	"github.com/google/uuid"
+)
+
+const defaultWelcomeDocsURL = "https://github.com/go-botx/sendyxmail"
+
+type welcomeConfig struct {
+	Enabled bool
+	DocsURL string
+}
+
+var welcome = welcomeConfig{
+	Enabled: true,
+	DocsURL: defaultWelcomeDocsURL,
+}
+
+// sendWelcome posts usage instructions to group chat the bot was added to.
+func sendWelcome(b *botx.Bot, chatId uuid.UUID, chatType string, locale string) {
+	if !welcome.Enabled || chatType == string(models.ChatTypeChat) {
+		return
+	}
+	commands := []string{}
+	for _, command := range []models.StatusResponseCommand{commandMute, commandUnmute, commandStatus, commandSenders, commandSubscribe, commandUnsubscribe, commandQuiet, commandDigest, commandOnCall, commandAlias, commandChatAddr} {
+		commands = append(commands, fmt.Sprintf("• `%s` - %s", command.Body, getLocalizedMessage(locale, "command"+command.Body)))
+	}
+	text := fmt.Sprintf(getLocalizedMessage(locale, "welcome"),
+		chatId.String()+groupChatMailSuffix,
+		strings.Join(commands, "\n"))
+	if welcome.DocsURL != "" {
+		text += "\n\n" + fmt.Sprintf(getLocalizedMessage(locale, "welcome_docs"), welcome.DocsURL)
+	}
+	message, err := models.NewNDRequest(chatId, text)
+	if err != nil {
+		return
+	}
+	b.SendMessageAsync(message)
+}